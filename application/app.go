@@ -3,28 +3,51 @@ package app
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
 	"os"
-
+	"strconv"
 	"time"
 
 	"github.com/superlogarifm/goCalc-v3/internal/auth"
 	"github.com/superlogarifm/goCalc-v3/internal/calculator"
+	"github.com/superlogarifm/goCalc-v3/internal/fleet"
+	"github.com/superlogarifm/goCalc-v3/internal/grpc/agentserver"
 	"github.com/superlogarifm/goCalc-v3/internal/http/handlers"
 	"github.com/superlogarifm/goCalc-v3/internal/http/middleware"
 	"github.com/superlogarifm/goCalc-v3/internal/storage"
-	postgresrepo "github.com/superlogarifm/goCalc-v3/internal/storage/postgres"
 
-	"gorm.io/driver/postgres"
+	"google.golang.org/grpc"
 	"gorm.io/gorm"
 )
 
 type Config struct {
-	Host          string
-	Port          string
-	DatabaseURL   string // Строка подключения к БД
-	JWTSecretKey  string // Секретный ключ для JWT
-	TokenDuration time.Duration
+	Host           string
+	Port           string
+	GRPCPort       string // порт gRPC-протокола агентов; пустой выключает его
+	DatabaseURL    string // Строка подключения к БД
+	JWTSecretKey   string // Секретный ключ для JWT
+	AgentSecret    string // общий секрет агентов; пустой выключает их проверку
+	AdminSecret    string // секрет управления агентами; без него при AgentSecret оно недоступно
+	TokenDuration  time.Duration
+	AutoMigrate    bool // применять миграции при запуске
+	AgentAPI       bool // принимать удалённых агентов на /internal/...
+	InternalWorker bool // вычислять задачи внутри процесса
+}
+
+// envBool читает логическую переменную окружения, при отсутствии или
+// ошибке разбора возвращая значение по умолчанию
+func envBool(name string, def bool) bool {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Warning: Invalid %s %q. Using default %v.\n", name, value, def)
+		return def
+	}
+	return parsed
 }
 
 func loadConfig() Config {
@@ -53,12 +76,32 @@ func loadConfig() Config {
 		tokenDuration = 24 * time.Hour // По умолчанию 24 часа
 	}
 
+	agentAPI := envBool("AGENT_API", false)
+	agentSecret := os.Getenv("AGENT_SECRET")
+	if agentAPI && agentSecret == "" {
+		log.Println("Warning: AGENT_SECRET is not set, agent endpoints accept unauthenticated requests.")
+	}
+	adminSecret := os.Getenv("ADMIN_SECRET")
+	if agentAPI && agentSecret != "" && adminSecret == "" {
+		log.Println("Warning: ADMIN_SECRET is not set, admin agent endpoints are disabled.")
+	}
+	internalWorker := envBool("INTERNAL_WORKER", true)
+	if !agentAPI && !internalWorker {
+		log.Println("Warning: both AGENT_API and INTERNAL_WORKER are disabled, expressions will never be computed.")
+	}
+
 	return Config{
-		Host:          host,
-		Port:          port,
-		DatabaseURL:   dbURL,
-		JWTSecretKey:  jwtSecret,
-		TokenDuration: tokenDuration,
+		Host:           host,
+		Port:           port,
+		GRPCPort:       os.Getenv("GRPC_PORT"),
+		DatabaseURL:    dbURL,
+		JWTSecretKey:   jwtSecret,
+		AgentSecret:    agentSecret,
+		AdminSecret:    adminSecret,
+		TokenDuration:  tokenDuration,
+		AutoMigrate:    envBool("AUTO_MIGRATE", true),
+		AgentAPI:       agentAPI,
+		InternalWorker: internalWorker,
 	}
 }
 
@@ -70,36 +113,73 @@ type App struct {
 	taskManager      *calculator.TaskManager
 	authHandlers     *handlers.AuthHandlers
 	calculateHandler *handlers.CalculateHandler
+	agentHandler     *handlers.AgentHandler
+	agentServer      *agentserver.Server
+	fleet            *fleet.Registry
+	stopEviction     func()
+	stopWorker       func()
 	authMiddleware   *middleware.AuthMiddleware
 	httpServer       *http.Server
+	grpcServer       *grpc.Server
 }
 
 func NewApp() *App {
 	config := loadConfig()
 
-	db, err := gorm.Open(postgres.Open(config.DatabaseURL), &gorm.Config{})
+	db, repos, err := openStorage(config.DatabaseURL)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
-	log.Println("Database connection established.")
-	userRepo := postgresrepo.NewPGUserRepository(db)
-	log.Println("Running database migrations...")
-	err = userRepo.AutoMigrate()
-	if err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+	if db == nil {
+		log.Println("Warning: using in-memory storage, data will be lost on shutdown.")
+	} else {
+		log.Println("Database connection established.")
+	}
+	userRepo := repos.users
+	if db != nil {
+		if err := prepareSchema(db, config.AutoMigrate); err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
 	}
-	log.Println("Migrations completed.")
 
 	authService, err := auth.NewAuthService(config.JWTSecretKey, config.TokenDuration)
 	if err != nil {
 		log.Fatalf("Failed to create auth service: %v", err)
 	}
 
-	taskManager := calculator.NewTaskManager()
-	taskManager.StartInternalWorker()
+	taskManager, err := calculator.NewTaskManagerWithStorage(repos.expressions, repos.tasks)
+	if err != nil {
+		log.Fatalf("Failed to create task manager: %v", err)
+	}
+	var stopWorker func()
+	if config.InternalWorker {
+		stopWorker = taskManager.StartInternalWorker()
+	}
 
 	authHandlers := handlers.NewAuthHandlers(authService, userRepo)
 	calculateHandler := handlers.NewCalculateHandler(taskManager)
+	var agentHandler *handlers.AgentHandler
+	var agentServer *agentserver.Server
+	var registry *fleet.Registry
+	if config.AgentAPI {
+		registry = fleet.NewRegistry(fleet.HeartbeatTTLFromEnv(), func(agentID string) {
+			taskManager.ReleaseAgentTasks(agentID)
+		})
+		var agentAuth *auth.AgentAuth
+		if config.AgentSecret != "" {
+			if agentAuth, err = auth.NewAgentAuth(config.AgentSecret); err != nil {
+				log.Fatalf("Failed to create agent auth: %v", err)
+			}
+		}
+		var adminAuth *auth.AdminAuth
+		if config.AdminSecret != "" {
+			if adminAuth, err = auth.NewAdminAuth(config.AdminSecret); err != nil {
+				log.Fatalf("Failed to create admin auth: %v", err)
+			}
+		}
+		agentHandler = handlers.NewAgentHandler(taskManager, registry, agentAuth, adminAuth)
+		agentServer = agentserver.New(taskManager, registry, agentAuth)
+	}
 	authMiddleware := middleware.NewAuthMiddleware(authService)
 
 	return &App{
@@ -110,11 +190,17 @@ func NewApp() *App {
 		taskManager:      taskManager,
 		authHandlers:     authHandlers,
 		calculateHandler: calculateHandler,
+		agentHandler:     agentHandler,
+		agentServer:      agentServer,
+		fleet:            registry,
+		stopWorker:       stopWorker,
 		authMiddleware:   authMiddleware,
 	}
 }
 
-func (a *App) StartServer() {
+// routes собирает обработчики сервиса: публичные, защищённые токеном
+// и, если включены, эндпоинты агентов
+func (a *App) routes() http.Handler {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/api/v1/register", a.authHandlers.Register)
@@ -122,18 +208,47 @@ func (a *App) StartServer() {
 
 	calculateMux := http.NewServeMux()
 	calculateMux.HandleFunc("/api/v1/calculate", a.calculateHandler.HandleCalculate)
-	calculateMux.HandleFunc("/api/v1/expressions", a.calculateHandler.HandleGetExpressions)     // Маршрут для GET /api/v1/expressions
-	calculateMux.HandleFunc("/api/v1/expressions/", a.calculateHandler.HandleGetExpressionByID) // Маршрут для GET /api/v1/expressions/{id}
+	calculateMux.HandleFunc("/api/v1/expressions", a.calculateHandler.HandleGetExpressions)  // Маршрут для GET /api/v1/expressions
+	calculateMux.HandleFunc("/api/v1/expressions/", a.calculateHandler.HandleExpressionByID) // GET, DELETE /api/v1/expressions/{id} и POST /api/v1/expressions/{id}/cancel
 
 	protectedHandler := a.authMiddleware.Authenticate(calculateMux)
 	mux.Handle("/api/v1/calculate", protectedHandler)
 	mux.Handle("/api/v1/expressions", protectedHandler)
 	mux.Handle("/api/v1/expressions/", protectedHandler)
 
+	// протокол агентов из cmd/agent: вместо пользовательского токена — AGENT_SECRET и токены агентов
+	if a.agentHandler != nil {
+		a.agentHandler.RegisterRoutes(mux)
+		log.Println("Agent API enabled on /internal/task, /internal/tasks, /internal/results, /internal/leases and /internal/agents.")
+	}
+	return mux
+}
+
+func (a *App) StartServer() {
 	serverAddr := a.config.Host + ":" + a.config.Port
 	a.httpServer = &http.Server{
 		Addr:    serverAddr,
-		Handler: mux,
+		Handler: a.routes(),
+	}
+
+	if a.fleet != nil {
+		a.stopEviction = a.fleet.StartEvictionLoop(a.fleet.HeartbeatInterval())
+	}
+
+	// тот же протокол агентов поверх gRPC: задачи присылаются агенту без опроса
+	if a.agentServer != nil && a.config.GRPCPort != "" {
+		grpcAddr := a.config.Host + ":" + a.config.GRPCPort
+		lis, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			log.Fatalf("Could not listen on %s: %v\n", grpcAddr, err)
+		}
+		a.grpcServer = a.agentServer.NewGRPCServer()
+		log.Printf("Starting agent gRPC server on %s\n", grpcAddr)
+		go func() {
+			if err := a.grpcServer.Serve(lis); err != nil {
+				log.Printf("Agent gRPC server stopped: %v\n", err)
+			}
+		}()
 	}
 
 	log.Printf("Starting server on %s\n", serverAddr)
@@ -145,22 +260,42 @@ func (a *App) StartServer() {
 	log.Println("Server started.")
 }
 
+// Shutdown останавливает всё, что меняет выражения, — HTTP, gRPC и встроенный
+// воркер, — затем дописывает изменения в базу и закрывает её.
 func (a *App) Shutdown(ctx context.Context) error {
 	log.Println("Shutting down server...")
-	sqlDB, err := a.db.DB()
-	if err == nil {
-		log.Println("Closing database connection...")
-		if err := sqlDB.Close(); err != nil {
-			log.Printf("Error closing database: %v\n", err)
-		} else {
-			log.Println("Database connection closed.")
-		}
-	} else {
-		log.Printf("Error getting underlying DB connection for closing: %v\n", err)
+	if a.stopEviction != nil {
+		a.stopEviction()
 	}
-
+	var shutdownErr error
 	if a.httpServer != nil {
-		return a.httpServer.Shutdown(ctx)
+		shutdownErr = a.httpServer.Shutdown(ctx)
+	}
+	if a.grpcServer != nil {
+		// потоки агентов бессрочные, поэтому не ждём их завершения
+		a.grpcServer.Stop()
+	}
+	if a.stopWorker != nil {
+		a.stopWorker()
+	}
+	if a.taskManager != nil {
+		// дописываем в базу изменения, накопленные в очереди записи
+		if err := a.taskManager.Flush(ctx); err != nil {
+			log.Printf("Error persisting expressions before shutdown: %v\n", err)
+		}
+	}
+	if a.db != nil {
+		sqlDB, err := a.db.DB()
+		if err == nil {
+			log.Println("Closing database connection...")
+			if err := sqlDB.Close(); err != nil {
+				log.Printf("Error closing database: %v\n", err)
+			} else {
+				log.Println("Database connection closed.")
+			}
+		} else {
+			log.Printf("Error getting underlying DB connection for closing: %v\n", err)
+		}
 	}
-	return nil
+	return shutdownErr
 }
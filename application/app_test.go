@@ -0,0 +1,119 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/superlogarifm/goCalc-v3/internal/calculator"
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+)
+
+func newTestApp(t *testing.T, agentAPI bool) (*App, http.Handler) {
+	t.Helper()
+	t.Setenv("DATABASE_URL", memoryURL)
+	t.Setenv("JWT_SECRET_KEY", "test-secret")
+	t.Setenv("TOKEN_DURATION", "1h")
+	t.Setenv("AGENT_API", strconv.FormatBool(agentAPI))
+	t.Setenv("INTERNAL_WORKER", "false")
+
+	a := NewApp()
+	return a, a.routes()
+}
+
+func doJSON(t *testing.T, h http.Handler, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestApp_RemoteAgent(t *testing.T) {
+	_, h := newTestApp(t, true)
+
+	credentials := map[string]string{"login": "alice", "password": "secret1"}
+	if rr := doJSON(t, h, http.MethodPost, "/api/v1/register", "", credentials); rr.Code != http.StatusOK {
+		t.Fatalf("register: status %d, body %s", rr.Code, rr.Body)
+	}
+	rr := doJSON(t, h, http.MethodPost, "/api/v1/login", "", credentials)
+	var login struct{ Token string }
+	if err := json.NewDecoder(rr.Body).Decode(&login); err != nil || login.Token == "" {
+		t.Fatalf("login: status %d, error %v", rr.Code, err)
+	}
+
+	rr = doJSON(t, h, http.MethodPost, "/api/v1/calculate", login.Token, models.CalculateRequest{Expression: "(1+2)*3"})
+	var created struct {
+		ID string `json:"expression_id"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil || created.ID == "" {
+		t.Fatalf("calculate: status %d, error %v", rr.Code, err)
+	}
+
+	// удалённый агент забирает задачи и возвращает результаты
+	for {
+		rr := doJSON(t, h, http.MethodGet, "/internal/task", "", nil)
+		if rr.Code == http.StatusNotFound {
+			break
+		}
+		var taskResponse models.TaskResponse
+		if err := json.NewDecoder(rr.Body).Decode(&taskResponse); err != nil {
+			t.Fatalf("get task: status %d, error %v", rr.Code, err)
+		}
+		result := calculator.EvaluateTask(taskResponse.Task)
+		if rr := doJSON(t, h, http.MethodPost, "/internal/task", "", result); rr.Code != http.StatusOK {
+			t.Fatalf("post result: status %d, body %s", rr.Code, rr.Body)
+		}
+	}
+
+	rr = doJSON(t, h, http.MethodGet, "/api/v1/expressions/"+created.ID, login.Token, nil)
+	var exprResponse models.ExpressionResponse
+	if err := json.NewDecoder(rr.Body).Decode(&exprResponse); err != nil {
+		t.Fatalf("get expression: status %d, error %v", rr.Code, err)
+	}
+	if expr := exprResponse.Expression; expr.Status != models.StatusCompleted || expr.Result == nil || *expr.Result != 9 {
+		t.Errorf("expression = %+v, want completed with 9", expr)
+	}
+}
+
+func TestApp_AgentAPIDisabled(t *testing.T) {
+	_, h := newTestApp(t, false)
+
+	if rr := doJSON(t, h, http.MethodGet, "/internal/task", "", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("GET /internal/task: status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	// без токена защищённые маршруты недоступны
+	if rr := doJSON(t, h, http.MethodGet, "/api/v1/expressions", "", nil); rr.Code != http.StatusUnauthorized {
+		t.Errorf("GET /api/v1/expressions: status %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestApp_AgentSecret(t *testing.T) {
+	t.Setenv("AGENT_SECRET", "fleet-secret")
+	_, h := newTestApp(t, true)
+
+	if rr := doJSON(t, h, http.MethodGet, "/internal/task", "", nil); rr.Code != http.StatusUnauthorized {
+		t.Errorf("GET /internal/task without token: status %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+	rr := doJSON(t, h, http.MethodPost, "/internal/agents", "fleet-secret", models.AgentRegistration{Hostname: "calc-1", Workers: 1})
+	var registration models.AgentRegistrationResponse
+	if err := json.NewDecoder(rr.Body).Decode(&registration); err != nil || registration.Token == "" {
+		t.Fatalf("register agent: status %d, response %+v, error %v", rr.Code, registration, err)
+	}
+	if rr := doJSON(t, h, http.MethodGet, "/internal/task", registration.Token, nil); rr.Code != http.StatusNotFound {
+		t.Errorf("GET /internal/task with token: status %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,114 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/superlogarifm/goCalc-v3/internal/storage/migrations"
+
+	"gorm.io/gorm"
+)
+
+// ErrSchemaOutdated — в базе есть неприменённые миграции, а AUTO_MIGRATE выключен
+var ErrSchemaOutdated = errors.New("database schema is out of date, run `calc_service migrate up`")
+
+// prepareSchema применяет миграции при запуске или, если autoMigrate
+// выключен, проверяет, что схема актуальна.
+func prepareSchema(db *gorm.DB, autoMigrate bool) error {
+	m, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if !autoMigrate {
+		pending, err := m.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%w: %d pending migrations", ErrSchemaOutdated, len(pending))
+		}
+		return nil
+	}
+
+	log.Println("Running database migrations...")
+	applied, err := m.Up(ctx)
+	for _, migration := range applied {
+		log.Printf("Applied migration %04d_%s\n", migration.Version, migration.Name)
+	}
+	if err != nil {
+		return err
+	}
+	log.Println("Migrations completed.")
+	return nil
+}
+
+// RunMigrate выполняет команду migrate up|down|status для базы из DATABASE_URL.
+func RunMigrate(args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("usage: calc_service migrate up|down|status")
+	}
+	command := args[0]
+	if command != "up" && command != "down" && command != "status" {
+		return fmt.Errorf("unknown migrate command %q, want up, down or status", command)
+	}
+
+	config := loadConfig()
+	db, err := openDB(config.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	if db == nil {
+		fmt.Fprintln(out, "In-memory storage has no schema to migrate.")
+		return nil
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	m, err := migrations.New(db)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, migration := range applied {
+			fmt.Fprintf(out, "applied   %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(out, "Schema is up to date.")
+		}
+	case "down":
+		migration, err := m.Down(ctx)
+		if err != nil {
+			return err
+		}
+		if migration == nil {
+			fmt.Fprintln(out, "No migrations to roll back.")
+			return nil
+		}
+		fmt.Fprintf(out, "rolled back %04d_%s\n", migration.Version, migration.Name)
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(out, "%04d_%-40s %s\n", status.Version, status.Name, state)
+		}
+	}
+	return nil
+}
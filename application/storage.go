@@ -0,0 +1,74 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/superlogarifm/goCalc-v3/internal/storage"
+	"github.com/superlogarifm/goCalc-v3/internal/storage/memory"
+	postgresrepo "github.com/superlogarifm/goCalc-v3/internal/storage/postgres"
+	sqliterepo "github.com/superlogarifm/goCalc-v3/internal/storage/sqlite"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// repositories — хранилища сервиса для выбранного драйвера БД
+type repositories struct {
+	users       storage.UserRepository
+	expressions storage.ExpressionRepository
+	tasks       storage.TaskRepository
+}
+
+// sqliteDSN возвращает DSN SQLite, если DATABASE_URL указывает на файл:
+// sqlite:///abs/path.db, sqlite://rel/path.db или file:path.db?...
+func sqliteDSN(url string) (string, bool) {
+	switch {
+	case strings.HasPrefix(url, "sqlite://"):
+		return strings.TrimPrefix(url, "sqlite://"), true
+	case strings.HasPrefix(url, "file:"):
+		return url, true
+	}
+	return "", false
+}
+
+// memoryURL включает хранение в памяти процесса: данные теряются при остановке
+const memoryURL = "memory://"
+
+// openDB подключается к SQLite или PostgreSQL в зависимости от DATABASE_URL.
+// Для memory:// база не открывается и возвращается nil.
+func openDB(url string) (*gorm.DB, error) {
+	if url == memoryURL {
+		return nil, nil
+	}
+	if dsn, ok := sqliteDSN(url); ok {
+		return sqliterepo.Open(dsn)
+	}
+	return gorm.Open(postgres.Open(url), &gorm.Config{})
+}
+
+// openStorage открывает базу и создаёт репозитории для выбранного драйвера
+func openStorage(url string) (*gorm.DB, *repositories, error) {
+	db, err := openDB(url)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch {
+	case db == nil:
+		return nil, &repositories{
+			users:       memory.NewUserRepository(),
+			expressions: memory.NewExpressionRepository(),
+			tasks:       memory.NewTaskRepository(),
+		}, nil
+	case db.Dialector.Name() == "sqlite":
+		return db, &repositories{
+			users:       sqliterepo.NewUserRepository(db),
+			expressions: sqliterepo.NewExpressionRepository(db),
+			tasks:       sqliterepo.NewTaskRepository(db),
+		}, nil
+	}
+	return db, &repositories{
+		users:       postgresrepo.NewPGUserRepository(db),
+		expressions: postgresrepo.NewPGExpressionRepository(db),
+		tasks:       postgresrepo.NewPGTaskRepository(db),
+	}, nil
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+)
+
+// batchTransport раздаёт вычислителям задачи, полученные пачкой через
+// GET /internal/tasks, и отправляет их результаты пачками в POST /internal/results.
+// Один запрос задач приходится на всех свободных вычислителей, а результаты,
+// накопившиеся за время отправки предыдущей пачки, уходят одним запросом.
+type batchTransport struct {
+	agent      *Agent
+	idle       chan struct{} // вычислитель освободился и ждёт задачу
+	tasks      chan models.Task
+	results    chan models.TaskResult
+	retryDelay time.Duration // пауза перед повторной отправкой пачки
+}
+
+// maxResultBatch — сколько результатов оркестратор принимает за раз (handlers.MaxTaskBatch)
+const maxResultBatch = 100
+
+func newBatchTransport(agent *Agent, workers int) *batchTransport {
+	return &batchTransport{
+		agent:      agent,
+		idle:       make(chan struct{}, workers),
+		tasks:      make(chan models.Task, workers),
+		results:    make(chan models.TaskResult, workers),
+		retryDelay: 5 * time.Second,
+	}
+}
+
+// run запускает получение задач и отправку результатов
+func (b *batchTransport) run() {
+	go b.fetchLoop()
+	go b.submitLoop()
+}
+
+func (b *batchTransport) getTask() (*models.Task, error) {
+	b.idle <- struct{}{}
+	task := <-b.tasks
+	return &task, nil
+}
+
+// submitResult ставит результат в очередь на отправку; пачка, которую
+// не удалось доставить, отправляется повторно
+func (b *batchTransport) submitResult(result models.TaskResult) error {
+	b.results <- result
+	return nil
+}
+
+// fetchLoop запрашивает столько задач, сколько вычислителей свободно
+func (b *batchTransport) fetchLoop() {
+	idle := 0
+	for {
+		if idle == 0 {
+			<-b.idle
+			idle++
+		}
+	drain:
+		for {
+			select {
+			case <-b.idle:
+				idle++
+			default:
+				break drain
+			}
+		}
+
+		tasks, err := b.agent.getTasks(idle)
+		if err != nil {
+			log.Printf("Error getting tasks: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		if len(tasks) == 0 && b.agent.taskWait <= 0 {
+			time.Sleep(time.Second)
+		}
+		for _, task := range tasks {
+			b.tasks <- task
+			idle--
+		}
+	}
+}
+
+// submitLoop отправляет результаты, не дожидаясь заполнения пачки:
+// одиночный результат уходит сразу, чтобы не задерживать зависимые задачи.
+// Пачка, которую не удалось доставить, отправляется снова вместе с
+// результатами, накопившимися за паузу.
+func (b *batchTransport) submitLoop() {
+	var batch []models.TaskResult
+	for {
+		if len(batch) == 0 {
+			batch = append(batch, <-b.results)
+		}
+	drain:
+		for len(batch) < maxResultBatch {
+			select {
+			case result := <-b.results:
+				batch = append(batch, result)
+			default:
+				break drain
+			}
+		}
+
+		err := b.agent.submitResults(batch)
+		if err == nil || errors.Is(err, errResultsRejected) {
+			if err != nil {
+				log.Printf("Dropping %d results: %v", len(batch), err)
+			}
+			batch = nil
+			continue
+		}
+		log.Printf("Error submitting %d results: %v, retrying in %v", len(batch), err, b.retryDelay)
+		time.Sleep(b.retryDelay)
+	}
+}
+
+// getTasks запрашивает до limit задач одним запросом
+func (a *Agent) getTasks(limit int) ([]models.Task, error) {
+	url := a.orchestratorURL + "/internal/tasks?modes=" + a.modes + "&max=" + strconv.Itoa(limit)
+	if a.taskWait > 0 {
+		url += "&wait=" + a.taskWait.String()
+	}
+	req, err := a.newRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		if os.IsTimeout(err) || isConnectionRefused(err) {
+			log.Printf("Оркестратор недоступен, ожидание...")
+			time.Sleep(5 * time.Second)
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	case http.StatusUnauthorized:
+		return nil, errUnauthorized
+	default:
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var tasksResp models.TasksResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tasksResp); err != nil {
+		return nil, err
+	}
+	return tasksResp.Tasks, nil
+}
+
+// errResultsRejected — оркестратор отклонил пачку целиком, повтор не поможет
+var errResultsRejected = errors.New("orchestrator rejected results batch")
+
+// submitResults отправляет результаты одним запросом; отклонённые
+// оркестратором результаты пишутся в журнал. Ошибка означает, что пачка
+// не доставлена: её можно отправить снова, если это не errResultsRejected.
+func (a *Agent) submitResults(results []models.TaskResult) error {
+	checked := make([]models.TaskResult, len(results))
+	for i, result := range results {
+		checked[i] = checkResult(result)
+	}
+	body, err := json.Marshal(checked)
+	if err != nil {
+		return err
+	}
+
+	req, err := a.newRequest(http.MethodPost, a.orchestratorURL+"/internal/results", body)
+	if err != nil {
+		return err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return errUnauthorized
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return fmt.Errorf("%w: status code %d", errResultsRejected, resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var resultsResp models.TaskResultsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&resultsResp); err != nil {
+		return err
+	}
+	for _, status := range resultsResp.Results {
+		if status.Error != "" {
+			log.Printf("Orchestrator rejected result of task %s: %s", status.ID, status.Error)
+		}
+	}
+	return nil
+}
+
+// checkResult заменяет результат, который нельзя передать в JSON (например,
+// бесконечность), ошибкой задачи, чтобы он не сорвал отправку всей пачки
+func checkResult(result models.TaskResult) models.TaskResult {
+	if _, err := json.Marshal(result); err != nil {
+		errMsg := fmt.Sprintf("invalid result: %v", err)
+		log.Printf("Task %s: %s", result.ID, errMsg)
+		return models.TaskResult{ID: result.ID, LeaseID: result.LeaseID, Error: &errMsg}
+	}
+	return result
+}
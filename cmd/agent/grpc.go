@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/superlogarifm/goCalc-v3/internal/grpc/agentpb"
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+var errNotConnected = errors.New("gRPC stream to orchestrator is not connected")
+
+// grpcTransport получает задачи через поток AgentService.Connect: оркестратор
+// присылает их сам, как только у агента есть свободный вычислитель,
+// поэтому простаивающие вычислители не опрашивают его.
+type grpcTransport struct {
+	addr   string
+	secret string
+	tasks  chan models.Task
+
+	mu      sync.Mutex
+	reg     models.AgentRegistration // ID заполняется после первой регистрации
+	stream  agentpb.AgentService_ConnectClient
+	waiting int // вычислители, ждущие задачу; после переподключения сообщаются заново
+}
+
+func newGRPCTransport(addr, secret string, reg models.AgentRegistration) *grpcTransport {
+	return &grpcTransport{
+		addr:   addr,
+		secret: secret,
+		reg:    reg,
+		tasks:  make(chan models.Task, reg.Workers),
+	}
+}
+
+// run держит поток открытым и переподключается после обрыва
+func (t *grpcTransport) run() {
+	for {
+		err := t.session()
+		log.Printf("gRPC session with %s ended: %v, reconnecting...", t.addr, err)
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// session регистрирует агента в новом потоке и принимает задачи до его обрыва
+func (t *grpcTransport) session() error {
+	conn, err := grpc.Dial(t.addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if t.secret != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+t.secret)
+	}
+	stream, err := agentpb.NewAgentServiceClient(conn).Connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	reg := t.reg
+	t.mu.Unlock()
+	err = stream.Send(&agentpb.AgentMessage{Payload: &agentpb.AgentMessage_Register{Register: agentpb.FromRegistration(reg)}})
+	if err != nil {
+		return err
+	}
+	msg, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	registered := msg.GetRegistered()
+	if registered == nil {
+		return fmt.Errorf("unexpected first message from orchestrator: %T", msg.GetPayload())
+	}
+
+	t.mu.Lock()
+	t.reg.ID = registered.GetId()
+	t.stream = stream
+	if t.waiting > 0 {
+		err = stream.Send(readyMessage(t.waiting))
+	}
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		t.stream = nil
+		t.mu.Unlock()
+	}()
+	if err != nil {
+		return err
+	}
+
+	interval := time.Duration(registered.GetHeartbeatIntervalMs()) * time.Millisecond
+	log.Printf("Registered as agent %s over gRPC, heartbeat every %v", registered.GetId(), interval)
+	go t.heartbeatLoop(ctx, interval)
+
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		switch payload := msg.GetPayload().(type) {
+		case *agentpb.OrchestratorMessage_Task:
+			t.tasks <- payload.Task.Model()
+		case *agentpb.OrchestratorMessage_Ack:
+			if payload.Ack.GetError() != "" {
+				log.Printf("Orchestrator rejected result of task %s: %s", payload.Ack.GetTaskId(), payload.Ack.GetError())
+			}
+		}
+	}
+}
+
+func (t *grpcTransport) heartbeatLoop(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := t.send(&agentpb.AgentMessage{Payload: &agentpb.AgentMessage_Heartbeat{Heartbeat: &agentpb.Heartbeat{}}}); err != nil {
+				log.Printf("Error sending heartbeat: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// send отправляет сообщение в текущий поток; Send потока gRPC нельзя
+// вызывать из нескольких горутин одновременно
+func (t *grpcTransport) send(msg *agentpb.AgentMessage) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stream == nil {
+		return errNotConnected
+	}
+	return t.stream.Send(msg)
+}
+
+// getTask сообщает оркестратору о свободном вычислителе и ждёт задачу.
+// Без соединения запрос откладывается до переподключения.
+func (t *grpcTransport) getTask() (*models.Task, error) {
+	t.mu.Lock()
+	t.waiting++
+	if t.stream != nil {
+		// ошибку отправки обработает session: после переподключения
+		// ждущие вычислители сообщаются заново
+		t.stream.Send(readyMessage(1))
+	}
+	t.mu.Unlock()
+
+	task := <-t.tasks
+
+	t.mu.Lock()
+	t.waiting--
+	t.mu.Unlock()
+	return &task, nil
+}
+
+func (t *grpcTransport) submitResult(result models.TaskResult) error {
+	return t.send(&agentpb.AgentMessage{Payload: &agentpb.AgentMessage_Result{Result: agentpb.FromTaskResult(result)}})
+}
+
+func readyMessage(slots int) *agentpb.AgentMessage {
+	return &agentpb.AgentMessage{Payload: &agentpb.AgentMessage_Ready{Ready: &agentpb.Ready{Slots: int32(slots)}}}
+}
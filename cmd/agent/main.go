@@ -3,7 +3,9 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -12,19 +14,69 @@ import (
 	"sync"
 	"time"
 
+	"github.com/superlogarifm/goCalc-v3/internal/calculator"
 	"github.com/superlogarifm/goCalc-v3/internal/models"
 )
 
+// transport — канал связи агента с оркестратором: HTTP-запросы по одной задаче
+// реализует сам Agent, пачки задач — batchTransport, поток gRPC — grpcTransport
+type transport interface {
+	getTask() (*models.Task, error)
+	submitResult(result models.TaskResult) error
+}
+
 type Agent struct {
 	orchestratorURL string
+	modes           string // режимы вычислений, которые агент сообщает оркестратору
 	client          *http.Client
+	secret          string        // общий секрет AGENT_SECRET для регистрации
+	taskWait        time.Duration // сколько оркестратор держит запрос задачи, если очередь пуста
+	transport       transport
+
+	mu                sync.Mutex
+	id                string // выдаётся оркестратором при регистрации
+	token             string // токен агента для запросов задач, результатов и heartbeat
+	heartbeatInterval time.Duration
+}
+
+var errUnauthorized = errors.New("orchestrator rejected agent credentials")
+
+// envBool читает логическую переменную окружения, при ошибке возвращая значение по умолчанию
+func envBool(name string, def bool) bool {
+	if val := os.Getenv(name); val != "" {
+		if parsed, err := strconv.ParseBool(val); err == nil {
+			return parsed
+		}
+	}
+	return def
+}
+
+// DefaultTaskWait — ожидание задачи в GET /internal/task?wait= по умолчанию
+const DefaultTaskWait = 30 * time.Second
+
+// taskWaitFromEnv читает ожидание задачи из TASK_WAIT_MS; 0 выключает long polling
+func taskWaitFromEnv() time.Duration {
+	if val := os.Getenv("TASK_WAIT_MS"); val != "" {
+		if ms, err := strconv.ParseInt(val, 10, 64); err == nil && ms >= 0 {
+			return time.Duration(ms) * time.Millisecond
+		}
+	}
+	return DefaultTaskWait
 }
 
 func NewAgent(orchestratorURL string) *Agent {
-	return &Agent{
+	var modes []string
+	for _, mode := range calculator.SupportedModes() {
+		modes = append(modes, string(mode))
+	}
+
+	a := &Agent{
 		orchestratorURL: orchestratorURL,
+		modes:           strings.Join(modes, ","),
+		taskWait:        DefaultTaskWait,
 		client: &http.Client{
-			Timeout: 30 * time.Second,
+			// запас сверх DefaultTaskWait, чтобы long polling не обрывался таймаутом клиента
+			Timeout: DefaultTaskWait + 30*time.Second,
 			Transport: &http.Transport{
 				MaxIdleConns:        100,
 				IdleConnTimeout:     90 * time.Second,
@@ -35,10 +87,142 @@ func NewAgent(orchestratorURL string) *Agent {
 			},
 		},
 	}
+	a.transport = a
+	return a
+}
+
+// registration описывает агента для регистрации у оркестратора
+func (a *Agent) registration(workers int) models.AgentRegistration {
+	hostname, _ := os.Hostname()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return models.AgentRegistration{
+		ID:         a.id,
+		Hostname:   hostname,
+		Workers:    workers,
+		Operations: calculator.OperationNames(),
+		Modes:      calculator.SupportedModes(),
+	}
+}
+
+// register сообщает оркестратору о запуске агента. ID из AGENT_ID сохраняется
+// между перезапусками; без него оркестратор назначает ID сам.
+func (a *Agent) register(workers int) error {
+	body, err := json.Marshal(a.registration(workers))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, a.orchestratorURL+"/internal/agents", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.secret != "" {
+		req.Header.Set("Authorization", "Bearer "+a.secret)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("%w: check AGENT_SECRET", errUnauthorized)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var regResp models.AgentRegistrationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&regResp); err != nil {
+		return err
+	}
+	interval := time.Duration(regResp.HeartbeatIntervalMs) * time.Millisecond
+	a.mu.Lock()
+	a.id = regResp.ID
+	a.token = regResp.Token
+	a.heartbeatInterval = interval
+	a.mu.Unlock()
+	log.Printf("Registered as agent %s, heartbeat every %v", regResp.ID, interval)
+	return nil
+}
+
+// heartbeat подтверждает, что агент жив. Если оркестратор агента не знает
+// (исключил или перезапустился) или отозвал его токен, агент регистрируется заново.
+func (a *Agent) heartbeat(workers int) error {
+	a.mu.Lock()
+	id := a.id
+	a.mu.Unlock()
+
+	req, err := a.newRequest(http.MethodPost, a.orchestratorURL+"/internal/agents/"+id+"/heartbeat", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent, http.StatusOK:
+		return nil
+	case http.StatusUnauthorized, http.StatusNotFound:
+		log.Printf("Orchestrator does not know agent %s, registering again", id)
+		return a.register(workers)
+	}
+	return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+}
+
+func (a *Agent) heartbeatLoop(workers int) {
+	for {
+		a.mu.Lock()
+		interval := a.heartbeatInterval
+		a.mu.Unlock()
+		if interval <= 0 {
+			interval = 5 * time.Second
+		}
+		time.Sleep(interval)
+
+		if err := a.heartbeat(workers); err != nil {
+			log.Printf("Error sending heartbeat: %v", err)
+		}
+	}
+}
+
+// newRequest добавляет к запросу ID и токен агента, если он зарегистрирован
+func (a *Agent) newRequest(method, url string, body []byte) (*http.Request, error) {
+	var reader io.Reader = http.NoBody
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	a.mu.Lock()
+	if a.id != "" {
+		req.Header.Set(models.AgentIDHeader, a.id)
+	}
+	if a.token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	}
+	a.mu.Unlock()
+	return req, nil
 }
 
 func (a *Agent) getTask() (*models.Task, error) {
-	resp, err := a.client.Get(a.orchestratorURL + "/internal/task")
+	url := a.orchestratorURL + "/internal/task?modes=" + a.modes
+	if a.taskWait > 0 {
+		url += "&wait=" + a.taskWait.String()
+	}
+	req, err := a.newRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := a.client.Do(req)
 	if err != nil {
 		if os.IsTimeout(err) || isConnectionRefused(err) {
 			log.Printf("Оркестратор недоступен, ожидание...")
@@ -52,6 +236,9 @@ func (a *Agent) getTask() (*models.Task, error) {
 	if resp.StatusCode == http.StatusNotFound {
 		return nil, nil
 	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, errUnauthorized
+	}
 
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
@@ -71,16 +258,16 @@ func isConnectionRefused(err error) bool {
 }
 
 func (a *Agent) submitResult(result models.TaskResult) error {
-	body, err := json.Marshal(result)
+	body, err := json.Marshal(checkResult(result))
 	if err != nil {
 		return err
 	}
 
-	resp, err := a.client.Post(
-		a.orchestratorURL+"/internal/task",
-		"application/json",
-		bytes.NewBuffer(body),
-	)
+	req, err := a.newRequest(http.MethodPost, a.orchestratorURL+"/internal/task", body)
+	if err != nil {
+		return err
+	}
+	resp, err := a.client.Do(req)
 	if err != nil {
 		if os.IsTimeout(err) || isConnectionRefused(err) {
 			log.Printf("Оркестратор недоступен при отправке результата, повторная попытка...")
@@ -91,6 +278,9 @@ func (a *Agent) submitResult(result models.TaskResult) error {
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errUnauthorized
+	}
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
@@ -99,79 +289,56 @@ func (a *Agent) submitResult(result models.TaskResult) error {
 }
 
 func (a *Agent) processTask(task models.Task) error {
-	time.Sleep(time.Duration(task.OperationTime) * time.Millisecond) // Имитация длительного вычисления
-
-	taskResult := models.TaskResult{ID: task.ID}
-
-	if strings.HasPrefix(task.Arg1, "task:") || strings.HasPrefix(task.Arg2, "task:") {
-		log.Printf("Task %s (ExprID: %s) has unresolved dependencies, returning to queue", task.ID, task.ExpressionID)
-		// В этом случае агент не должен отправлять результат, т.к. задача не его.
-		// Оркестратор должен сам перевыставить задачу, когда зависимости разрешатся.
-		// Однако, для консистентности, можно было бы отправить ошибку "unresolved_dependencies".
-		// Но текущая логика оркестратора в GetNextTask уже обрабатывает это, возвращая задачу в очередь.
-		// Поэтому здесь просто выходим, не отправляя результат.
+	if calculator.HasUnresolvedArgs(task) {
+		// оркестратор выдаёт только задачи с известными аргументами,
+		// такая задача — признак рассинхронизации, результат не отправляем
+		log.Printf("Task %s (ExprID: %s) has unresolved dependencies, skipping", task.ID, task.ExpressionID)
 		return nil
 	}
 
-	arg1, err1 := strconv.ParseFloat(task.Arg1, 64)
-	arg2, err2 := strconv.ParseFloat(task.Arg2, 64)
-
-	if err1 != nil || err2 != nil {
-		errMsg := fmt.Sprintf("invalid arguments for task %s (ExprID: %s): arg1='%s', arg2='%s', err1=%v, err2=%v", task.ID, task.ExpressionID, task.Arg1, task.Arg2, err1, err2)
-		log.Printf("Error processing task: %s", errMsg)
-		taskResult.Error = &errMsg
-		return a.submitResult(taskResult)
-	}
-
-	var operationResult float64
-	var calcError error
+	time.Sleep(time.Duration(task.OperationTime) * time.Millisecond) // Имитация длительного вычисления
 
-	switch task.Operation {
-	case "+":
-		operationResult = arg1 + arg2
-	case "-":
-		operationResult = arg1 - arg2
-	case "*":
-		operationResult = arg1 * arg2
-	case "/":
-		if arg2 == 0 {
-			calcError = fmt.Errorf("division by zero")
-		} else {
-			operationResult = arg1 / arg2
+	taskResult := calculator.EvaluateTask(task)
+	if taskResult.Error != nil {
+		log.Printf("Error processing task %s (ExprID: %s): %s(%v): %s", task.ID, task.ExpressionID, task.Operation, calculator.TaskArgs(task), *taskResult.Error)
+		if err := a.transport.submitResult(taskResult); err != nil {
+			return err
 		}
-	default:
-		calcError = fmt.Errorf("unknown operation: %s", task.Operation)
+		return fmt.Errorf("task %s failed: %s", task.ID, *taskResult.Error)
 	}
 
-	if calcError != nil {
-		errMsg := fmt.Sprintf("error calculating task %s (ExprID: %s): %v", task.ID, task.ExpressionID, calcError)
-		log.Printf("Error processing task: %s", errMsg)
-		taskResult.Error = &errMsg
-		return a.submitResult(taskResult)
-	}
+	log.Printf("Task %s (ExprID: %s) completed: %s(%v) = %s", task.ID, task.ExpressionID, task.Operation, calculator.TaskArgs(task), formatResult(taskResult))
+	return a.transport.submitResult(taskResult)
+}
 
-	taskResult.Result = operationResult
-	log.Printf("Task %s (ExprID: %s) completed: %f %s %f = %f", task.ID, task.ExpressionID, arg1, task.Operation, arg2, operationResult)
-	return a.submitResult(taskResult)
+func formatResult(result models.TaskResult) string {
+	if result.Value != "" {
+		return result.Value
+	}
+	return strconv.FormatFloat(result.Result, 'g', -1, 64)
 }
 
 func (a *Agent) worker(wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	for {
-		task, err := a.getTask()
+		task, err := a.transport.getTask()
 		if err != nil {
 			log.Printf("Error getting task: %v", err)
+			time.Sleep(time.Second)
 			continue
 		}
 
 		if task == nil {
-			time.Sleep(time.Second)
+			// при long polling оркестратор сам выдержал паузу, пока очередь была пуста
+			if a.taskWait <= 0 {
+				time.Sleep(time.Second)
+			}
 			continue
 		}
 
 		if err := a.processTask(*task); err != nil {
-			log.Printf("Error submitting result for task %s (ExprID: %s): %v", task.ID, task.ExpressionID, err)
+			log.Printf("Error processing task %s (ExprID: %s): %v", task.ID, task.ExpressionID, err)
 		}
 	}
 }
@@ -190,9 +357,43 @@ func main() {
 	}
 
 	agent := NewAgent(orchestratorURL)
-	var wg sync.WaitGroup
+	agent.id = os.Getenv("AGENT_ID")
+	agent.secret = os.Getenv("AGENT_SECRET")
+	agent.taskWait = taskWaitFromEnv()
+	agent.client.Timeout = agent.taskWait + 30*time.Second
+
+	switch mode := os.Getenv("AGENT_TRANSPORT"); mode {
+	case "", "http":
+		for {
+			err := agent.register(computingPower)
+			if err == nil {
+				break
+			}
+			log.Printf("Failed to register with orchestrator: %v, retrying...", err)
+			time.Sleep(5 * time.Second)
+		}
+		go agent.heartbeatLoop(computingPower)
+		// по умолчанию задачи и результаты передаются пачками на всех вычислителей
+		if envBool("AGENT_BATCH", true) {
+			batch := newBatchTransport(agent, computingPower)
+			agent.transport = batch
+			batch.run()
+		}
+		log.Printf("Starting agent with %d workers, connecting to %s", computingPower, orchestratorURL)
+	case "grpc":
+		addr := os.Getenv("ORCHESTRATOR_GRPC_ADDR")
+		if addr == "" {
+			addr = "localhost:9090"
+		}
+		grpcTransport := newGRPCTransport(addr, agent.secret, agent.registration(computingPower))
+		agent.transport = grpcTransport
+		go grpcTransport.run()
+		log.Printf("Starting agent with %d workers, connecting to %s over gRPC", computingPower, addr)
+	default:
+		log.Fatalf("Unknown AGENT_TRANSPORT %q, want http or grpc", mode)
+	}
 
-	log.Printf("Starting agent with %d workers, connecting to %s", computingPower, orchestratorURL)
+	var wg sync.WaitGroup
 	for i := 0; i < computingPower; i++ {
 		wg.Add(1)
 		go agent.worker(&wg)
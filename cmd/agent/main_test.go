@@ -2,10 +2,20 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/superlogarifm/goCalc-v3/internal/auth"
+	"github.com/superlogarifm/goCalc-v3/internal/calculator"
+	"github.com/superlogarifm/goCalc-v3/internal/fleet"
+	"github.com/superlogarifm/goCalc-v3/internal/grpc/agentserver"
+	"github.com/superlogarifm/goCalc-v3/internal/http/handlers"
 	"github.com/superlogarifm/goCalc-v3/internal/models"
 )
 
@@ -108,6 +118,40 @@ func TestAgent_ProcessTask(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "унарный минус",
+			task: models.Task{
+				ID:            "11",
+				Arg1:          "4",
+				Operation:     "neg",
+				OperationTime: 10,
+			},
+			wantErr:   false,
+			wantValue: -4,
+		},
+		{
+			name: "степень",
+			task: models.Task{
+				ID:            "12",
+				Arg1:          "2",
+				Arg2:          "10",
+				Operation:     "^",
+				OperationTime: 10,
+			},
+			wantErr:   false,
+			wantValue: 1024,
+		},
+		{
+			name: "вызов функции",
+			task: models.Task{
+				ID:            "13",
+				Args:          []string{"1", "7", "3"},
+				Operation:     "max",
+				OperationTime: 10,
+			},
+			wantErr:   false,
+			wantValue: 7,
+		},
 		{
 			name: "зависимость в аргументе 1",
 			task: models.Task{
@@ -166,6 +210,23 @@ func TestAgent_ProcessTask(t *testing.T) {
 	}
 }
 
+func TestAgent_ProcessTaskUnresolved(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	}))
+	defer server.Close()
+
+	// задачу с неизвестным аргументом агент отбрасывает, не имитируя вычисление
+	task := models.Task{ID: "1", Arg1: "task:123", Arg2: "3", Operation: "+", OperationTime: 10000}
+	start := time.Now()
+	if err := NewAgent(server.URL).processTask(task); err != nil {
+		t.Errorf("processTask() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("processTask() took %v for a task with unresolved arguments", elapsed)
+	}
+}
+
 func TestAgent_GetTask(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/internal/task" && r.Method == http.MethodGet {
@@ -225,3 +286,393 @@ func TestAgent_SubmitResult(t *testing.T) {
 		t.Errorf("submitResult() error = %v", err)
 	}
 }
+
+func TestAgent_ProcessDecimalTask(t *testing.T) {
+	var submitted models.TaskResult
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&submitted)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+	}))
+	defer server.Close()
+
+	agent := NewAgent(server.URL)
+	err := agent.processTask(models.Task{
+		ID:            "1",
+		Arg1:          "0.1",
+		Arg2:          "0.2",
+		Operation:     "+",
+		OperationTime: 10,
+		Mode:          models.ModeDecimal,
+		Scale:         16,
+	})
+	if err != nil {
+		t.Fatalf("processTask() error = %v", err)
+	}
+
+	if submitted.Value != "0.3" || submitted.Error != nil {
+		t.Errorf("Unexpected result: %+v", submitted)
+	}
+}
+
+func TestAgent_RegisterAndHeartbeat(t *testing.T) {
+	tm := calculator.NewTaskManager()
+	registry := fleet.NewRegistry(time.Minute, func(agentID string) { tm.ReleaseAgentTasks(agentID) })
+	mux := http.NewServeMux()
+	handlers.NewAgentHandler(tm, registry, nil, nil).RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	agent := NewAgent(server.URL)
+	if err := agent.register(3); err != nil {
+		t.Fatalf("register() error = %v", err)
+	}
+	if agent.id == "" || agent.heartbeatInterval != 20*time.Second {
+		t.Fatalf("agent id = %q, heartbeat interval = %v", agent.id, agent.heartbeatInterval)
+	}
+	agents := registry.Agents()
+	if len(agents) != 1 || agents[0].ID != agent.id || agents[0].Workers != 3 || len(agents[0].Operations) == 0 {
+		t.Fatalf("registry agents = %+v", agents)
+	}
+
+	// задача выдаётся зарегистрированному агенту
+	if _, err := tm.CreateExpression(models.CalculateRequest{Expression: "2+3"}); err != nil {
+		t.Fatal(err)
+	}
+	task, err := agent.getTask()
+	if err != nil || task == nil {
+		t.Fatalf("getTask() = %v, %v", task, err)
+	}
+	if leases := tm.Leases(); len(leases) != 1 || leases[0].AgentID != agent.id {
+		t.Errorf("Leases() = %+v, want lease held by %s", leases, agent.id)
+	}
+
+	// после исключения агент регистрируется заново с тем же ID, задача возвращается в очередь
+	id := agent.id
+	if err := registry.Remove(id); err != nil {
+		t.Fatal(err)
+	}
+	if leases := tm.Leases(); len(leases) != 0 {
+		t.Errorf("Leases() = %+v after eviction, want none", leases)
+	}
+	if err := agent.heartbeat(3); err != nil {
+		t.Fatalf("heartbeat() error = %v", err)
+	}
+	if agents := registry.Agents(); len(agents) != 1 || agents[0].ID != id {
+		t.Errorf("registry agents = %+v, want %s registered again", agents, id)
+	}
+}
+
+func TestAgent_Authenticated(t *testing.T) {
+	tm := calculator.NewTaskManager()
+	registry := fleet.NewRegistry(time.Minute, func(agentID string) { tm.ReleaseAgentTasks(agentID) })
+	agentAuth, err := auth.NewAgentAuth("fleet-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	handlers.NewAgentHandler(tm, registry, agentAuth, nil).RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	if _, err := tm.CreateExpression(models.CalculateRequest{Expression: "2+3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// без секрета агент не регистрируется и не получает задач
+	stranger := NewAgent(server.URL)
+	if err := stranger.register(1); !errors.Is(err, errUnauthorized) {
+		t.Errorf("register() without secret error = %v, want %v", err, errUnauthorized)
+	}
+	if _, err := stranger.getTask(); !errors.Is(err, errUnauthorized) {
+		t.Errorf("getTask() without token error = %v, want %v", err, errUnauthorized)
+	}
+
+	agent := NewAgent(server.URL)
+	agent.secret = "fleet-secret"
+	if err := agent.register(1); err != nil {
+		t.Fatalf("register() error = %v", err)
+	}
+	if agent.token == "" {
+		t.Fatal("register() did not store the agent token")
+	}
+	task, err := agent.getTask()
+	if err != nil || task == nil {
+		t.Fatalf("getTask() = %v, %v", task, err)
+	}
+	if err := agent.processTask(*task); err != nil {
+		t.Fatalf("processTask() error = %v", err)
+	}
+	if err := agent.heartbeat(1); err != nil {
+		t.Errorf("heartbeat() error = %v", err)
+	}
+	if expressions := tm.GetAllExpressions(); len(expressions) != 1 || expressions[0].Status != models.StatusCompleted {
+		t.Errorf("expressions = %+v, want completed", expressions)
+	}
+
+	// после исключения токен отозван: агент регистрируется заново по секрету
+	if err := registry.Remove(agent.id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := agent.getTask(); !errors.Is(err, errUnauthorized) {
+		t.Errorf("getTask() of removed agent error = %v, want %v", err, errUnauthorized)
+	}
+	if err := agent.heartbeat(1); err != nil {
+		t.Errorf("heartbeat() error = %v", err)
+	}
+	if !registry.Registered(agent.id) {
+		t.Errorf("agent %s did not register again after removal", agent.id)
+	}
+}
+
+func TestAgent_GRPCTransport(t *testing.T) {
+	tm := calculator.NewTaskManager()
+	registry := fleet.NewRegistry(time.Minute, func(agentID string) { tm.ReleaseAgentTasks(agentID) })
+	agentAuth, err := auth.NewAgentAuth("fleet-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := agentserver.New(tm, registry, agentAuth).NewGRPCServer()
+	go gs.Serve(lis)
+	defer gs.Stop()
+
+	id, err := tm.CreateExpression(models.CalculateRequest{Expression: "(1+2)*3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	agent := NewAgent("")
+	agent.id = "calc-grpc"
+	grpcTransport := newGRPCTransport(lis.Addr().String(), "fleet-secret", agent.registration(1))
+	agent.transport = grpcTransport
+	go grpcTransport.run()
+
+	// задачи приходят по потоку, агент считает их и отправляет результаты
+	for i := 0; i < 2; i++ {
+		task, err := agent.transport.getTask()
+		if err != nil {
+			t.Fatalf("getTask() error = %v", err)
+		}
+		if err := agent.processTask(*task); err != nil {
+			t.Fatalf("processTask() error = %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		expr, _ := tm.GetExpression(id)
+		if expr.Status == models.StatusCompleted {
+			if expr.Result == nil || *expr.Result != 9 {
+				t.Errorf("expression = %+v, want 9", expr)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expression = %+v, want completed", expr)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if agents := registry.Agents(); len(agents) != 1 || agents[0].ID != "calc-grpc" {
+		t.Errorf("registry agents = %+v, want calc-grpc", agents)
+	}
+}
+
+func TestAgent_LongPolling(t *testing.T) {
+	tm := calculator.NewTaskManager()
+	registry := fleet.NewRegistry(time.Minute, nil)
+	mux := http.NewServeMux()
+	handlers.NewAgentHandler(tm, registry, nil, nil).RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	agent := NewAgent(server.URL)
+	if agent.taskWait != DefaultTaskWait {
+		t.Fatalf("taskWait = %v, want long polling by default", agent.taskWait)
+	}
+
+	got := make(chan *models.Task, 1)
+	go func() {
+		task, err := agent.getTask()
+		if err != nil {
+			t.Errorf("getTask() error = %v", err)
+		}
+		got <- task
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if _, err := tm.CreateExpression(models.CalculateRequest{Expression: "2+3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case task := <-got:
+		if task == nil || task.Operation != "+" {
+			t.Errorf("getTask() = %+v, want 2+3", task)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("getTask() did not return the task created while waiting")
+	}
+}
+
+func TestAgent_BatchTransport(t *testing.T) {
+	t.Setenv("TIME_ADDITION_MS", "10")
+	t.Setenv("TIME_MULTIPLICATIONS_MS", "10")
+	tm := calculator.NewTaskManager()
+	registry := fleet.NewRegistry(time.Minute, nil)
+	mux := http.NewServeMux()
+	handlers.NewAgentHandler(tm, registry, nil, nil).RegisterRoutes(mux)
+
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		mux.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	id, err := tm.CreateExpression(models.CalculateRequest{Expression: "(1+2)+(3*4)+(5*6)"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const workers = 3
+	agent := NewAgent(server.URL)
+	agent.taskWait = time.Second
+	batch := newBatchTransport(agent, workers)
+	agent.transport = batch
+	batch.run()
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go agent.worker(&wg)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		expr, _ := tm.GetExpression(id)
+		if expr.Status == models.StatusCompleted {
+			if expr.Result == nil || *expr.Result != 45 {
+				t.Errorf("expression = %+v, want 45", expr)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expression = %+v, want completed", expr)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// задачи и результаты шли только через пакетные эндпоинты
+	mu.Lock()
+	defer mu.Unlock()
+	if requests["/internal/task"] != 0 || requests["/internal/tasks"] == 0 || requests["/internal/results"] == 0 {
+		t.Errorf("requests = %v, want only /internal/tasks and /internal/results", requests)
+	}
+	if requests["/internal/results"] > 5 {
+		t.Errorf("%d result requests for 5 tasks", requests["/internal/results"])
+	}
+}
+
+func TestAgent_BatchRetry(t *testing.T) {
+	t.Setenv("TIME_ADDITION_MS", "10")
+	tm := calculator.NewTaskManager()
+	mux := http.NewServeMux()
+	handlers.NewAgentHandler(tm, fleet.NewRegistry(time.Minute, nil), nil, nil).RegisterRoutes(mux)
+
+	var mu sync.Mutex
+	failed := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fail := r.URL.Path == "/internal/results" && !failed
+		if fail {
+			failed = true
+		}
+		mu.Unlock()
+		if fail {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	id, err := tm.CreateExpression(models.CalculateRequest{Expression: "2+2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	agent := NewAgent(server.URL)
+	agent.taskWait = time.Second
+	batch := newBatchTransport(agent, 1)
+	batch.retryDelay = 10 * time.Millisecond
+	agent.transport = batch
+	batch.run()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go agent.worker(&wg)
+
+	// первая пачка не принята сервером, результат доставляется повторно
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		expr, _ := tm.GetExpression(id)
+		if expr.Status == models.StatusCompleted {
+			if expr.Result == nil || *expr.Result != 4 {
+				t.Errorf("expression = %+v, want 4", expr)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expression = %+v, want completed after retry", expr)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAgent_SubmitResultsInvalid(t *testing.T) {
+	tm := calculator.NewTaskManager()
+	mux := http.NewServeMux()
+	handlers.NewAgentHandler(tm, fleet.NewRegistry(time.Minute, nil), nil, nil).RegisterRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	broken, err := tm.CreateExpression(models.CalculateRequest{Expression: "2+2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid, err := tm.CreateExpression(models.CalculateRequest{Expression: "3+3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := tm.GetNextTask()
+	second, _ := tm.GetNextTask()
+	if first == nil || second == nil {
+		t.Fatalf("GetNextTask() returned no task")
+	}
+
+	// бесконечность не сериализуется в JSON: она становится ошибкой своей
+	// задачи и не мешает отправить остальные результаты пачки
+	results := []models.TaskResult{
+		{ID: first.ID, LeaseID: first.LeaseID, Result: math.Inf(1)},
+		{ID: second.ID, LeaseID: second.LeaseID, Result: 6},
+	}
+	if first.ExpressionID != broken {
+		results[0].Result, results[1].Result = 6, math.Inf(1)
+		broken, valid = valid, broken
+	}
+	agent := NewAgent(server.URL)
+	if err := agent.submitResults(results); err != nil {
+		t.Fatalf("submitResults() error = %v", err)
+	}
+
+	if expr, _ := tm.GetExpression(broken); expr.Status != models.StatusError {
+		t.Errorf("expression with infinite result = %+v, want error", expr)
+	}
+	if expr, _ := tm.GetExpression(valid); expr.Status != models.StatusCompleted || expr.Result == nil || *expr.Result != 6 {
+		t.Errorf("expression = %+v, want completed with 6", expr)
+	}
+}
@@ -12,6 +12,14 @@ import (
 )
 
 func main() {
+	// calc_service migrate up|down|status управляет схемой БД и завершается
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := application.RunMigrate(os.Args[2:], os.Stdout); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
 	app := application.NewApp()
 	app.StartServer()
 
@@ -4,22 +4,69 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"strings"
 
+	"github.com/superlogarifm/goCalc-v3/internal/auth"
 	"github.com/superlogarifm/goCalc-v3/internal/calculator"
+	"github.com/superlogarifm/goCalc-v3/internal/fleet"
+	"github.com/superlogarifm/goCalc-v3/internal/grpc/agentserver"
+	"github.com/superlogarifm/goCalc-v3/internal/http/handlers"
 	"github.com/superlogarifm/goCalc-v3/internal/models"
 )
 
 type Orchestrator struct {
 	taskManager *calculator.TaskManager
+	fleet       *fleet.Registry
+	agents      *handlers.AgentHandler
+	agentServer *agentserver.Server // протокол агентов поверх gRPC
 }
 
 func NewOrchestrator() *Orchestrator {
+	tm := calculator.NewTaskManager()
+	registry := fleet.NewRegistry(fleet.HeartbeatTTLFromEnv(), func(agentID string) {
+		tm.ReleaseAgentTasks(agentID)
+	})
+	agentAuth := agentAuthFromEnv()
 	return &Orchestrator{
-		taskManager: calculator.NewTaskManager(),
+		taskManager: tm,
+		fleet:       registry,
+		agents:      handlers.NewAgentHandler(tm, registry, agentAuth, adminAuthFromEnv(agentAuth != nil)),
+		agentServer: agentserver.New(tm, registry, agentAuth),
+	}
+}
+
+// agentAuthFromEnv включает проверку агентов, если задан AGENT_SECRET
+func agentAuthFromEnv() *auth.AgentAuth {
+	secret := os.Getenv("AGENT_SECRET")
+	if secret == "" {
+		log.Println("Warning: AGENT_SECRET is not set, agent endpoints accept unauthenticated requests.")
+		return nil
+	}
+	agentAuth, err := auth.NewAgentAuth(secret)
+	if err != nil {
+		log.Fatalf("Failed to create agent auth: %v", err)
 	}
+	return agentAuth
+}
+
+// adminAuthFromEnv включает проверку административных запросов, если задан
+// ADMIN_SECRET. Без него при включённой проверке агентов эти запросы отклоняются.
+func adminAuthFromEnv(agentAuthEnabled bool) *auth.AdminAuth {
+	secret := os.Getenv("ADMIN_SECRET")
+	if secret == "" {
+		if agentAuthEnabled {
+			log.Println("Warning: ADMIN_SECRET is not set, admin agent endpoints are disabled.")
+		}
+		return nil
+	}
+	adminAuth, err := auth.NewAdminAuth(secret)
+	if err != nil {
+		log.Fatalf("Failed to create admin auth: %v", err)
+	}
+	return adminAuth
 }
 
 func (o *Orchestrator) handleCalculate(w http.ResponseWriter, r *http.Request) {
@@ -34,9 +81,11 @@ func (o *Orchestrator) handleCalculate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id, err := o.taskManager.CreateExpression(req.Expression)
+	id, err := o.taskManager.CreateExpression(req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(calculator.NewErrorResponse(err))
 		return
 	}
 
@@ -72,46 +121,6 @@ func (o *Orchestrator) handleGetExpression(w http.ResponseWriter, r *http.Reques
 	http.Error(w, "Expression not found", http.StatusNotFound)
 }
 
-func (o *Orchestrator) handleGetTask(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	if task, ok := o.taskManager.GetNextTask(); ok {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(models.TaskResponse{Task: *task})
-		return
-	}
-
-	http.Error(w, "No tasks available", http.StatusNotFound)
-}
-
-func (o *Orchestrator) handleTaskResult(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var result models.TaskResult
-	if err := json.NewDecoder(r.Body).Decode(&result); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusUnprocessableEntity)
-		return
-	}
-
-	if err := o.taskManager.UpdateTaskResult(result); err != nil {
-		if err.Error() == "task not found" {
-			http.Error(w, err.Error(), http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
-}
-
 func loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		log.Printf("%s %s", r.Method, r.URL.Path)
@@ -129,16 +138,25 @@ func main() {
 	mux.HandleFunc("/api/v1/expressions/", o.handleGetExpression)
 
 	// Внутренние endpoints для агентов
-	mux.HandleFunc("/internal/task", func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodGet:
-			o.handleGetTask(w, r)
-		case http.MethodPost:
-			o.handleTaskResult(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	o.agents.RegisterRoutes(mux)
+	stopEviction := o.fleet.StartEvictionLoop(o.fleet.HeartbeatInterval())
+	defer stopEviction()
+
+	// gRPC-транспорт агентов включается переменной GRPC_PORT
+	if grpcPort := os.Getenv("GRPC_PORT"); grpcPort != "" {
+		lis, err := net.Listen("tcp", ":"+grpcPort)
+		if err != nil {
+			log.Fatal(err)
 		}
-	})
+		grpcServer := o.agentServer.NewGRPCServer()
+		defer grpcServer.Stop()
+		log.Printf("Starting agent gRPC server on port %s", grpcPort)
+		go func() {
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Printf("Agent gRPC server stopped: %v", err)
+			}
+		}()
+	}
 
 	handler := loggingMiddleware(mux)
 
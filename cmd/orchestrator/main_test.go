@@ -3,11 +3,14 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/superlogarifm/goCalc-v3/internal/calculator"
 	"github.com/superlogarifm/goCalc-v3/internal/models"
 )
 
@@ -34,6 +37,16 @@ func TestHandleCalculate(t *testing.T) {
 			body:       `{"expression": ""}`,
 			wantStatus: http.StatusUnprocessableEntity,
 		},
+		{
+			name:       "выражение с переменными",
+			body:       `{"expression": "price * qty", "variables": {"price": 2.5, "qty": 4}}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "переменная без значения",
+			body:       `{"expression": "price * qty", "variables": {"price": 2.5}}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
 		{
 			name:       "невалидный JSON",
 			body:       `{"expression": 2+2}`,
@@ -147,7 +160,7 @@ func TestHandleTaskOperations(t *testing.T) {
 
 	req, _ = http.NewRequest("GET", "/internal/task", nil)
 	rr = httptest.NewRecorder()
-	http.HandlerFunc(o.handleGetTask).ServeHTTP(rr, req)
+	http.HandlerFunc(o.agents.HandleGetTask).ServeHTTP(rr, req)
 
 	if status := rr.Code; status != http.StatusOK {
 		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
@@ -159,15 +172,16 @@ func TestHandleTaskOperations(t *testing.T) {
 	}
 
 	taskResult := models.TaskResult{
-		ID:     taskResponse.Task.ID,
-		Result: 4,
+		ID:      taskResponse.Task.ID,
+		LeaseID: taskResponse.Task.LeaseID,
+		Result:  4,
 	}
 
 	taskResultJSON, _ := json.Marshal(taskResult)
 	req, _ = http.NewRequest("POST", "/internal/task", bytes.NewBuffer(taskResultJSON))
 	req.Header.Set("Content-Type", "application/json")
 	rr = httptest.NewRecorder()
-	http.HandlerFunc(o.handleTaskResult).ServeHTTP(rr, req)
+	http.HandlerFunc(o.agents.HandleTaskResult).ServeHTTP(rr, req)
 
 	if status := rr.Code; status != http.StatusOK {
 		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
@@ -201,3 +215,416 @@ func TestHandleTaskOperations(t *testing.T) {
 		t.Errorf("No completed expressions found")
 	}
 }
+
+func TestHandleCalculate_ParseError(t *testing.T) {
+	o := NewOrchestrator()
+
+	req, _ := http.NewRequest("POST", "/api/v1/calculate", bytes.NewBufferString(`{"expression": "2 + x"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(o.handleCalculate).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusUnprocessableEntity {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusUnprocessableEntity)
+	}
+
+	var response models.ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if response.Code != "unbound_variable" || response.Position == nil || *response.Position != 4 || response.Token != "x" {
+		t.Errorf("Unexpected error response: %+v", response)
+	}
+}
+
+func TestHandleGetTask_Modes(t *testing.T) {
+	o := NewOrchestrator()
+
+	req, _ := http.NewRequest("POST", "/api/v1/calculate", bytes.NewBufferString(`{"expression": "1/3", "mode": "rational"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(o.handleCalculate).ServeHTTP(rr, req)
+
+	req, _ = http.NewRequest("GET", "/internal/task", nil)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(o.agents.HandleGetTask).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("float-only agent: got status %v want %v", status, http.StatusNotFound)
+	}
+
+	req, _ = http.NewRequest("GET", "/internal/task?modes=float,rational", nil)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(o.agents.HandleGetTask).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("rational agent: got status %v want %v", status, http.StatusOK)
+	}
+
+	var taskResponse models.TaskResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &taskResponse); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if taskResponse.Task.Mode != models.ModeRational {
+		t.Errorf("Task mode = %q, want %q", taskResponse.Task.Mode, models.ModeRational)
+	}
+}
+
+func TestHandleGetTask_Wait(t *testing.T) {
+	o := NewOrchestrator()
+
+	for _, wait := range []string{"soon", "-1s"} {
+		req, _ := http.NewRequest("GET", "/internal/task?wait="+wait, nil)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(o.agents.HandleGetTask).ServeHTTP(rr, req)
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("wait=%s: got status %v want %v", wait, status, http.StatusBadRequest)
+		}
+	}
+
+	// пустая очередь: ответ 404 после ожидания
+	start := time.Now()
+	req, _ := http.NewRequest("GET", "/internal/task?wait=50ms", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(o.agents.HandleGetTask).ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("empty queue: got status %v want %v", status, http.StatusNotFound)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("empty queue: responded after %v, want to wait 50ms", elapsed)
+	}
+
+	// задача, появившаяся во время ожидания, выдаётся сразу
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		req, _ := http.NewRequest("GET", "/internal/task?wait=10s", nil)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(o.agents.HandleGetTask).ServeHTTP(rr, req)
+		done <- rr
+	}()
+	time.Sleep(20 * time.Millisecond)
+	req, _ = http.NewRequest("POST", "/api/v1/calculate", bytes.NewBufferString(`{"expression": "2+2"}`))
+	http.HandlerFunc(o.handleCalculate).ServeHTTP(httptest.NewRecorder(), req)
+
+	select {
+	case rr := <-done:
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("waiting agent: got status %v want %v", status, http.StatusOK)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("waiting agent was not woken up by the new task")
+	}
+}
+
+func TestHandleBatch(t *testing.T) {
+	o := NewOrchestrator()
+	mux := http.NewServeMux()
+	o.agents.RegisterRoutes(mux)
+
+	req, _ := http.NewRequest("POST", "/api/v1/calculate", bytes.NewBufferString(`{"expression": "(1+2)+(3*4)+(5*6)"}`))
+	http.HandlerFunc(o.handleCalculate).ServeHTTP(httptest.NewRecorder(), req)
+
+	for _, max := range []string{"0", "-2", "many"} {
+		req, _ := http.NewRequest("GET", "/internal/tasks?max="+max, nil)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("max=%s: got status %v want %v", max, status, http.StatusBadRequest)
+		}
+	}
+
+	// в пачку попадают все три независимые операции
+	req, _ = http.NewRequest("GET", "/internal/tasks?max=10", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("get tasks: got status %v want %v", status, http.StatusOK)
+	}
+	var tasksResponse models.TasksResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &tasksResponse); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(tasksResponse.Tasks) != 3 {
+		t.Fatalf("Tasks = %+v, want 3 independent tasks", tasksResponse.Tasks)
+	}
+
+	results := []models.TaskResult{{ID: "unknown", Result: 1}}
+	for _, task := range tasksResponse.Tasks {
+		results = append(results, calculator.EvaluateTask(task))
+	}
+	body, _ := json.Marshal(results)
+	req, _ = http.NewRequest("POST", "/internal/results", bytes.NewBuffer(body))
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("post results: got status %v want %v", status, http.StatusOK)
+	}
+	var resultsResponse models.TaskResultsResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resultsResponse); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(resultsResponse.Results) != 4 {
+		t.Fatalf("Results = %+v, want 4 statuses", resultsResponse.Results)
+	}
+	// неизвестная задача отклоняется, остальные результаты принимаются
+	if got := resultsResponse.Results[0]; got.ID != "unknown" || got.Status != "error" || got.Error == "" {
+		t.Errorf("Results[0] = %+v, want error for unknown task", got)
+	}
+	for _, got := range resultsResponse.Results[1:] {
+		if got.Status != "success" {
+			t.Errorf("result %+v, want success", got)
+		}
+	}
+
+	// результаты открыли следующую операцию
+	req, _ = http.NewRequest("GET", "/internal/tasks?max=10", nil)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if err := json.Unmarshal(rr.Body.Bytes(), &tasksResponse); err != nil || len(tasksResponse.Tasks) != 1 || tasksResponse.Tasks[0].Arg1 != "3" {
+		t.Errorf("next batch = %+v, %v, want 3+12", tasksResponse.Tasks, err)
+	}
+}
+
+func TestHandleGetLeases(t *testing.T) {
+	o := NewOrchestrator()
+
+	req, _ := http.NewRequest("POST", "/api/v1/calculate", bytes.NewBufferString(`{"expression": "2+2"}`))
+	req.Header.Set("Content-Type", "application/json")
+	http.HandlerFunc(o.handleCalculate).ServeHTTP(httptest.NewRecorder(), req)
+
+	req, _ = http.NewRequest("GET", "/internal/task", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(o.agents.HandleGetTask).ServeHTTP(rr, req)
+
+	var taskResponse models.TaskResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &taskResponse); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	req, _ = http.NewRequest("GET", "/internal/leases", nil)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(o.agents.HandleGetLeases).ServeHTTP(rr, req)
+
+	var leasesResponse models.LeasesResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &leasesResponse); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(leasesResponse.Leases) != 1 || leasesResponse.Leases[0].LeaseID != taskResponse.Task.LeaseID {
+		t.Fatalf("Leases = %+v, want lease %s", leasesResponse.Leases, taskResponse.Task.LeaseID)
+	}
+
+	// результат по чужой аренде отклоняется
+	taskResultJSON, _ := json.Marshal(models.TaskResult{ID: taskResponse.Task.ID, Result: 4, LeaseID: "stale"})
+	req, _ = http.NewRequest("POST", "/internal/task", bytes.NewBuffer(taskResultJSON))
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(o.agents.HandleTaskResult).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusConflict {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusConflict)
+	}
+}
+
+func TestHandleAgents(t *testing.T) {
+	o := NewOrchestrator()
+	mux := http.NewServeMux()
+	o.agents.RegisterRoutes(mux)
+
+	req, _ := http.NewRequest("POST", "/internal/agents", bytes.NewBufferString(`{"hostname": "calc-1", "workers": 2, "operations": ["+", "-"]}`))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusCreated {
+		t.Fatalf("register: got status %v want %v", status, http.StatusCreated)
+	}
+	var registration models.AgentRegistrationResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &registration); err != nil || registration.ID == "" || registration.HeartbeatIntervalMs <= 0 {
+		t.Fatalf("register response = %+v, %v", registration, err)
+	}
+
+	req, _ = http.NewRequest("POST", "/api/v1/calculate", bytes.NewBufferString(`{"expression": "2+2"}`))
+	http.HandlerFunc(o.handleCalculate).ServeHTTP(httptest.NewRecorder(), req)
+	req, _ = http.NewRequest("GET", "/internal/task", nil)
+	req.Header.Set(models.AgentIDHeader, registration.ID)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	var taskResponse models.TaskResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &taskResponse); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	req, _ = http.NewRequest("POST", "/internal/agents/"+registration.ID+"/heartbeat", nil)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusNoContent {
+		t.Errorf("heartbeat: got status %v want %v", status, http.StatusNoContent)
+	}
+
+	req, _ = http.NewRequest("GET", "/internal/agents", nil)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	var agentsResponse models.AgentsResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &agentsResponse); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(agentsResponse.Agents) != 1 {
+		t.Fatalf("Agents = %+v, want one agent", agentsResponse.Agents)
+	}
+	agent := agentsResponse.Agents[0]
+	if agent.Hostname != "calc-1" || agent.Workers != 2 || len(agent.InFlight) != 1 || agent.InFlight[0].TaskID != taskResponse.Task.ID {
+		t.Errorf("Agent = %+v, want calc-1 holding task %s", agent, taskResponse.Task.ID)
+	}
+
+	// исключение агента администратором возвращает его задачу в очередь
+	req, _ = http.NewRequest("DELETE", "/internal/agents/"+registration.ID, nil)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusNoContent {
+		t.Errorf("delete: got status %v want %v", status, http.StatusNoContent)
+	}
+	if leases := o.taskManager.Leases(); len(leases) != 0 {
+		t.Errorf("Leases = %+v after agent removal, want none", leases)
+	}
+
+	for _, tc := range []struct{ method, path string }{
+		{"POST", "/internal/agents/" + registration.ID + "/heartbeat"},
+		{"DELETE", "/internal/agents/" + registration.ID},
+	} {
+		req, _ = http.NewRequest(tc.method, tc.path, nil)
+		rr = httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if status := rr.Code; status != http.StatusNotFound {
+			t.Errorf("%s %s: got status %v want %v", tc.method, tc.path, status, http.StatusNotFound)
+		}
+	}
+}
+
+func TestHandleAgents_Auth(t *testing.T) {
+	t.Setenv("AGENT_SECRET", "fleet-secret")
+	t.Setenv("ADMIN_SECRET", "admin-secret")
+	o := NewOrchestrator()
+	mux := http.NewServeMux()
+	o.agents.RegisterRoutes(mux)
+
+	do := func(method, path, token string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	req, _ := http.NewRequest("POST", "/api/v1/calculate", bytes.NewBufferString(`{"expression": "2+2"}`))
+	http.HandlerFunc(o.handleCalculate).ServeHTTP(httptest.NewRecorder(), req)
+
+	if rr := do("POST", "/internal/agents", "wrong", `{"hostname": "calc-1"}`); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("register with wrong secret: got status %v want %v", rr.Code, http.StatusUnauthorized)
+	}
+	rr := do("POST", "/internal/agents", "fleet-secret", `{"hostname": "calc-1"}`)
+	var first models.AgentRegistrationResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &first); err != nil || rr.Code != http.StatusCreated || first.Token == "" {
+		t.Fatalf("register: status %v, response %+v, %v", rr.Code, first, err)
+	}
+	rr = do("POST", "/internal/agents", "fleet-secret", `{"hostname": "calc-2"}`)
+	var second models.AgentRegistrationResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &second); err != nil || second.Token == "" {
+		t.Fatalf("register: status %v, response %+v, %v", rr.Code, second, err)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		body       string
+		wantStatus int
+	}{
+		{"задача без токена", "GET", "/internal/task", "", "", http.StatusUnauthorized},
+		{"задача с чужим секретом", "GET", "/internal/task", "wrong", "", http.StatusUnauthorized},
+		{"результат без токена", "POST", "/internal/task", "", `{"id": "1", "result": 4}`, http.StatusUnauthorized},
+		{"задача по секрету вместо токена", "GET", "/internal/task", "fleet-secret", "", http.StatusUnauthorized},
+		{"heartbeat без токена", "POST", "/internal/agents/" + first.ID + "/heartbeat", "", "", http.StatusUnauthorized},
+		{"heartbeat чужим токеном", "POST", "/internal/agents/" + first.ID + "/heartbeat", second.Token, "", http.StatusForbidden},
+		{"heartbeat своим токеном", "POST", "/internal/agents/" + first.ID + "/heartbeat", first.Token, "", http.StatusNoContent},
+		{"список агентов по токену агента", "GET", "/internal/agents", first.Token, "", http.StatusUnauthorized},
+		{"список агентов по секрету агентов", "GET", "/internal/agents", "fleet-secret", "", http.StatusUnauthorized},
+		{"список агентов по секрету администратора", "GET", "/internal/agents", "admin-secret", "", http.StatusOK},
+		{"регистрация по секрету администратора", "POST", "/internal/agents", "admin-secret", `{"hostname": "calc-3"}`, http.StatusUnauthorized},
+		{"аренды без секрета", "GET", "/internal/leases", "", "", http.StatusUnauthorized},
+		{"аренды по секрету агентов", "GET", "/internal/leases", "fleet-secret", "", http.StatusUnauthorized},
+		{"аренды по секрету администратора", "GET", "/internal/leases", "admin-secret", "", http.StatusOK},
+		{"исключение агента токеном агента", "DELETE", "/internal/agents/" + second.ID, first.Token, "", http.StatusUnauthorized},
+		{"исключение агента секретом агентов", "DELETE", "/internal/agents/" + second.ID, "fleet-secret", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rr := do(tt.method, tt.path, tt.token, tt.body); rr.Code != tt.wantStatus {
+				t.Errorf("%s %s: got status %v want %v", tt.method, tt.path, rr.Code, tt.wantStatus)
+			}
+		})
+	}
+
+	// токен агента открывает выдачу задач, аренда записывается на него
+	rr = do("GET", "/internal/task", first.Token, "")
+	var taskResponse models.TaskResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &taskResponse); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("get task: status %v, %v", rr.Code, err)
+	}
+	if leases := o.taskManager.Leases(); len(leases) != 1 || leases[0].AgentID != first.ID {
+		t.Errorf("Leases = %+v, want lease held by %s", leases, first.ID)
+	}
+	result := fmt.Sprintf(`{"id": %q, "lease_id": %q, "result": 4}`, taskResponse.Task.ID, taskResponse.Task.LeaseID)
+	// аренда выдана первому агенту: второй не может сдать результат даже с её LeaseID
+	if rr := do("POST", "/internal/task", second.Token, result); rr.Code != http.StatusConflict {
+		t.Errorf("post result of another agent: got status %v want %v", rr.Code, http.StatusConflict)
+	}
+	if rr := do("POST", "/internal/results", second.Token, "["+result+"]"); !strings.Contains(rr.Body.String(), `"status":"error"`) {
+		t.Errorf("post results of another agent: got %s, want error status", rr.Body.String())
+	}
+	rr = do("POST", "/internal/task", first.Token, result)
+	if rr.Code != http.StatusOK {
+		t.Errorf("post result: got status %v want %v", rr.Code, http.StatusOK)
+	}
+
+	// токен исключённого агента больше не действует
+	if rr := do("DELETE", "/internal/agents/"+second.ID, "admin-secret", ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("delete: got status %v want %v", rr.Code, http.StatusNoContent)
+	}
+	for _, tc := range []struct{ method, path, body string }{
+		{"GET", "/internal/task", ""},
+		{"GET", "/internal/tasks", ""},
+		{"POST", "/internal/results", "[]"},
+		{"POST", "/internal/agents/" + second.ID + "/heartbeat", ""},
+	} {
+		if rr := do(tc.method, tc.path, second.Token, tc.body); rr.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s by removed agent: got status %v want %v", tc.method, tc.path, rr.Code, http.StatusUnauthorized)
+		}
+	}
+	if agents := o.fleet.Agents(); len(agents) != 1 || agents[0].ID != first.ID {
+		t.Errorf("Agents = %+v, want only %s", agents, first.ID)
+	}
+}
+
+func TestHandleAgents_AdminDisabled(t *testing.T) {
+	t.Setenv("AGENT_SECRET", "fleet-secret")
+	t.Setenv("ADMIN_SECRET", "")
+	o := NewOrchestrator()
+	mux := http.NewServeMux()
+	o.agents.RegisterRoutes(mux)
+
+	// без ADMIN_SECRET секрет агентов не открывает управление ими
+	for _, tc := range []struct{ method, path string }{
+		{"GET", "/internal/agents"},
+		{"GET", "/internal/leases"},
+		{"DELETE", "/internal/agents/agent-1"},
+	} {
+		req, _ := http.NewRequest(tc.method, tc.path, nil)
+		req.Header.Set("Authorization", "Bearer fleet-secret")
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("%s %s: got status %v want %v", tc.method, tc.path, rr.Code, http.StatusUnauthorized)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidAgentToken = errors.New("invalid agent token")
+
+// AgentAuth проверяет агентов. Общий секрет AGENT_SECRET нужен для регистрации;
+// при регистрации агент получает собственный токен
+// вида "<agentID>.<HMAC-SHA256(agentID)>". Токен не хранится на сервере;
+// отзывает его исключение агента из парка, которое проверяет middleware.
+type AgentAuth struct {
+	secret []byte
+}
+
+func NewAgentAuth(secret string) (*AgentAuth, error) {
+	if secret == "" {
+		return nil, errors.New("agent secret cannot be empty")
+	}
+	return &AgentAuth{secret: []byte(secret)}, nil
+}
+
+// CheckSecret сравнивает переданный секрет с общим за постоянное время
+func (a *AgentAuth) CheckSecret(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(secret), a.secret) == 1
+}
+
+// IssueToken выдаёт токен агенту с данным ID
+func (a *AgentAuth) IssueToken(agentID string) string {
+	return agentID + "." + a.sign(agentID)
+}
+
+// ValidateToken проверяет токен агента и возвращает его ID
+func (a *AgentAuth) ValidateToken(token string) (string, error) {
+	sep := strings.LastIndex(token, ".")
+	if sep <= 0 {
+		return "", ErrInvalidAgentToken
+	}
+	agentID, signature := token[:sep], token[sep+1:]
+	if !hmac.Equal([]byte(signature), []byte(a.sign(agentID))) {
+		return "", ErrInvalidAgentToken
+	}
+	return agentID, nil
+}
+
+func (a *AgentAuth) sign(agentID string) string {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte("agent:" + agentID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// AdminAuth проверяет секрет ADMIN_SECRET административных запросов к парку
+// агентов. Он отделён от AGENT_SECRET, который известен каждому агенту:
+// иначе любой агент мог бы исключать другие.
+type AdminAuth struct {
+	secret []byte
+}
+
+func NewAdminAuth(secret string) (*AdminAuth, error) {
+	if secret == "" {
+		return nil, errors.New("admin secret cannot be empty")
+	}
+	return &AdminAuth{secret: []byte(secret)}, nil
+}
+
+// CheckSecret сравнивает переданный секрет с административным за постоянное время
+func (a *AdminAuth) CheckSecret(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(secret), a.secret) == 1
+}
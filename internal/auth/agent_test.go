@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAgentAuth(t *testing.T) {
+	a, err := NewAgentAuth("fleet-secret")
+	if err != nil {
+		t.Fatalf("NewAgentAuth() error = %v", err)
+	}
+	other, _ := NewAgentAuth("another-secret")
+	token := a.IssueToken("agent-1")
+
+	tests := []struct {
+		name    string
+		token   string
+		wantID  string
+		wantErr bool
+	}{
+		{"выданный токен", token, "agent-1", false},
+		{"ID с точкой", a.IssueToken("calc.node-1"), "calc.node-1", false},
+		{"подменённый ID", "agent-2" + token[len("agent-1"):], "", true},
+		{"чужой секрет", other.IssueToken("agent-1"), "", true},
+		{"без подписи", "agent-1", "", true},
+		{"общий секрет вместо токена", "fleet-secret", "", true},
+		{"пустой токен", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := a.ValidateToken(tt.token)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidAgentToken) {
+					t.Errorf("ValidateToken(%q) error = %v, want %v", tt.token, err, ErrInvalidAgentToken)
+				}
+				return
+			}
+			if err != nil || id != tt.wantID {
+				t.Errorf("ValidateToken(%q) = %q, %v, want %q", tt.token, id, err, tt.wantID)
+			}
+		})
+	}
+
+	if !a.CheckSecret("fleet-secret") || a.CheckSecret("fleet-secret2") || a.CheckSecret("") {
+		t.Error("CheckSecret() accepted a wrong secret or rejected the right one")
+	}
+	if _, err := NewAgentAuth(""); err == nil {
+		t.Error("NewAgentAuth(\"\") error = nil, want error")
+	}
+}
@@ -0,0 +1,179 @@
+package calculator
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+)
+
+const (
+	// DefaultDecimalScale — число знаков после запятой в десятичном режиме по умолчанию
+	DefaultDecimalScale = 16
+	// MaxDecimalScale ограничивает точность, которую может запросить клиент
+	MaxDecimalScale = 100
+
+	// предельный показатель степени, который считается точно
+	maxExactExponent = 10000
+	// предельный размер числителя и знаменателя точной степени в битах
+	// (около 300 тысяч десятичных знаков): без него ((10^10000)^10000)^10000
+	// занимает вычислитель без ограничения памяти и времени
+	maxExactPowerBits = 1 << 20
+)
+
+type ratNumber struct {
+	r *big.Rat
+}
+
+// Float64 возвращает приближение значения; за пределами float64 это ±Inf,
+// поэтому в результаты задач и выражений оно не попадает (см. approximate)
+func (n ratNumber) Float64() float64 {
+	f, _ := n.r.Float64()
+	return f
+}
+
+// approximate возвращает значение точного режима в float64 или nil,
+// если оно вне диапазона float64
+func approximate(value string) *float64 {
+	r, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return nil
+	}
+	f, _ := r.Float64()
+	if math.IsInf(f, 0) {
+		return nil
+	}
+	return &f
+}
+
+func rat(n Value) *big.Rat {
+	return n.(ratNumber).r
+}
+
+// ratArithmetic содержит общие для точных режимов операции над big.Rat
+type ratArithmetic struct{}
+
+func (ratArithmetic) Parse(s string) (Value, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid number: %q", s)
+	}
+	return ratNumber{r}, nil
+}
+
+func (ratArithmetic) Add(a, b Value) (Value, error) {
+	return ratNumber{new(big.Rat).Add(rat(a), rat(b))}, nil
+}
+
+func (ratArithmetic) Sub(a, b Value) (Value, error) {
+	return ratNumber{new(big.Rat).Sub(rat(a), rat(b))}, nil
+}
+
+func (ratArithmetic) Mul(a, b Value) (Value, error) {
+	return ratNumber{new(big.Rat).Mul(rat(a), rat(b))}, nil
+}
+
+func (ratArithmetic) Div(a, b Value) (Value, error) {
+	if rat(b).Sign() == 0 {
+		return nil, fmt.Errorf("division by zero")
+	}
+	return ratNumber{new(big.Rat).Quo(rat(a), rat(b))}, nil
+}
+
+func (ratArithmetic) Neg(a Value) (Value, error) {
+	return ratNumber{new(big.Rat).Neg(rat(a))}, nil
+}
+
+func (ratArithmetic) Cmp(a, b Value) int {
+	return rat(a).Cmp(rat(b))
+}
+
+// exactPower возводит в целую степень без потери точности
+func exactPower(base, exp *big.Rat) (*big.Rat, error) {
+	n := exp.Num()
+	if n.Sign() < 0 && base.Sign() == 0 {
+		return nil, fmt.Errorf("division by zero")
+	}
+	if powerBits(base.Num(), n) > maxExactPowerBits || powerBits(base.Denom(), n) > maxExactPowerBits {
+		return nil, fmt.Errorf("power result is out of range: exceeds %d bits", maxExactPowerBits)
+	}
+
+	num := new(big.Int).Exp(base.Num(), new(big.Int).Abs(n), nil)
+	denom := new(big.Int).Exp(base.Denom(), new(big.Int).Abs(n), nil)
+	if n.Sign() < 0 {
+		num, denom = denom, num
+	}
+	if denom.Sign() < 0 {
+		num.Neg(num)
+		denom.Neg(denom)
+	}
+	return new(big.Rat).SetFrac(num, denom), nil
+}
+
+// powerBits оценивает сверху размер x^|n| в битах; n ограничен maxExactExponent
+func powerBits(x, n *big.Int) int64 {
+	if x.CmpAbs(big.NewInt(1)) <= 0 {
+		return 1
+	}
+	return int64(x.BitLen()) * new(big.Int).Abs(n).Int64()
+}
+
+func isExactExponent(exp *big.Rat) bool {
+	return exp.IsInt() && exp.Num().CmpAbs(big.NewInt(maxExactExponent)) <= 0
+}
+
+// DecimalArithmetic — точная десятичная арифметика с округлением до Scale знаков
+type DecimalArithmetic struct {
+	ratArithmetic
+	Scale int
+}
+
+func (DecimalArithmetic) Mode() models.CalculationMode { return models.ModeDecimal }
+
+// Format округляет число до Scale знаков и отбрасывает незначащие нули
+func (d DecimalArithmetic) Format(n Value) string {
+	s := rat(n).FloatString(d.Scale)
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(s, "0")
+		s = strings.TrimSuffix(s, ".")
+	}
+	if s == "-0" {
+		s = "0"
+	}
+	return s
+}
+
+func (DecimalArithmetic) FromFloat(f float64) (Value, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, fmt.Errorf("result is out of range: %g", f)
+	}
+	return ratNumber{new(big.Rat).SetFloat64(f)}, nil
+}
+
+// Pow считает целые степени точно, остальные — через float64
+func (d DecimalArithmetic) Pow(a, b Value) (Value, error) {
+	if isExactExponent(rat(b)) {
+		result, err := exactPower(rat(a), rat(b))
+		if err != nil {
+			return nil, err
+		}
+		return ratNumber{result}, nil
+	}
+	result, err := power(a.Float64(), b.Float64())
+	if err != nil {
+		return nil, err
+	}
+	return d.FromFloat(result)
+}
+
+func (d DecimalArithmetic) Sqrt(a Value) (Value, error) {
+	if rat(a).Sign() < 0 {
+		return nil, fmt.Errorf("square root of negative number: %s", d.Format(a))
+	}
+	root := new(big.Float).SetPrec(uint(d.Scale)*4 + 64).SetRat(rat(a))
+	root.Sqrt(root)
+	result, _ := root.Rat(nil)
+	return ratNumber{result}, nil
+}
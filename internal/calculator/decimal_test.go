@@ -0,0 +1,183 @@
+package calculator
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+)
+
+func TestDecimalArithmetic(t *testing.T) {
+	tests := []struct {
+		name    string
+		op      string
+		args    []string
+		scale   int
+		want    string
+		wantErr bool
+	}{
+		{name: "сложение без потери точности", op: "+", args: []string{"0.1", "0.2"}, scale: 16, want: "0.3"},
+		{name: "вычитание", op: "-", args: []string{"1", "0.9"}, scale: 16, want: "0.1"},
+		{name: "умножение", op: "*", args: []string{"19.99", "3"}, scale: 16, want: "59.97"},
+		{name: "деление с округлением", op: "/", args: []string{"1", "3"}, scale: 4, want: "0.3333"},
+		{name: "округление половины от нуля", op: "/", args: []string{"2", "3"}, scale: 2, want: "0.67"},
+		{name: "деление на ноль", op: "/", args: []string{"1", "0"}, scale: 16, wantErr: true},
+		{name: "целая степень", op: "^", args: []string{"1.1", "2"}, scale: 16, want: "1.21"},
+		{name: "отрицательная степень", op: "^", args: []string{"2", "-2"}, scale: 16, want: "0.25"},
+		{name: "унарный минус", op: OpNegate, args: []string{"0.5"}, scale: 16, want: "-0.5"},
+		{name: "max", op: "max", args: []string{"0.1", "0.30", "0.2"}, scale: 16, want: "0.3"},
+		{name: "sqrt", op: "sqrt", args: []string{"2"}, scale: 10, want: "1.4142135624"},
+		{name: "некорректный аргумент", op: "+", args: []string{"abc", "1"}, scale: 16, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ar := DecimalArithmetic{Scale: tt.scale}
+			got, err := Evaluate(ar, tt.op, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Evaluate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && ar.Format(got) != tt.want {
+				t.Errorf("Evaluate() = %v, want %v", ar.Format(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestExactPowerLimit(t *testing.T) {
+	// 10^10000 — допустимая точная степень, но её степень 10000 уже нет
+	huge := "1" + strings.Repeat("0", 10000)
+	tests := []struct {
+		name    string
+		ar      Arithmetic
+		args    []string
+		wantErr bool
+	}{
+		{name: "десятичная степень в пределах", ar: DecimalArithmetic{Scale: 16}, args: []string{"10", "10000"}},
+		{name: "рациональная степень в пределах", ar: RationalArithmetic{}, args: []string{"10", "-10000"}},
+		{name: "десятичная степень большого числа", ar: DecimalArithmetic{Scale: 16}, args: []string{huge, "10000"}, wantErr: true},
+		{name: "рациональная степень большого числа", ar: RationalArithmetic{}, args: []string{huge, "10000"}, wantErr: true},
+		{name: "большой знаменатель", ar: RationalArithmetic{}, args: []string{"1/" + huge, "10000"}, wantErr: true},
+		{name: "отрицательная степень большого числа", ar: RationalArithmetic{}, args: []string{huge, "-10000"}, wantErr: true},
+		{name: "единица в большой степени", ar: RationalArithmetic{}, args: []string{"-1", "10000"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Evaluate(tt.ar, OpPower, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Evaluate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), "out of range") {
+				t.Errorf("Evaluate() error = %v, want out of range", err)
+			}
+		})
+	}
+}
+
+func TestTaskManager_DecimalMode(t *testing.T) {
+	tm := NewTaskManager()
+	id, err := tm.CreateExpression(models.CalculateRequest{Expression: "0.1+0.2", Mode: models.ModeDecimal})
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+
+	task, ok := tm.GetNextTask()
+	if !ok {
+		t.Fatalf("GetNextTask() returned no task")
+	}
+	if task.Mode != models.ModeDecimal || task.Scale != DefaultDecimalScale {
+		t.Fatalf("Task mode = %v/%d, want decimal/%d", task.Mode, task.Scale, DefaultDecimalScale)
+	}
+
+	if err := tm.UpdateTaskResult(EvaluateTask(*task)); err != nil {
+		t.Fatalf("UpdateTaskResult() error = %v", err)
+	}
+
+	expr, ok := tm.GetExpression(id)
+	if !ok {
+		t.Fatalf("GetExpression() returned no expression")
+	}
+	if expr.Status != models.StatusCompleted || expr.Value != "0.3" {
+		t.Errorf("Expression = %+v, want completed with value 0.3", expr)
+	}
+}
+
+func TestTaskManager_ExactOutOfRange(t *testing.T) {
+	// значения вне диапазона float64 передаются только точной строкой
+	huge := "1" + strings.Repeat("0", 400)
+	tests := []struct {
+		name       string
+		expression string
+		mode       models.CalculationMode
+		want       string
+		wantResult *float64
+	}{
+		{name: "десятичная степень", expression: "10^400", mode: models.ModeDecimal, want: huge},
+		{name: "десятичный литерал", expression: "-" + huge + " * 2", mode: models.ModeDecimal, want: "-2" + huge[1:]},
+		{name: "десятичный литерал в пределах после деления", expression: huge + " / " + huge[:400], mode: models.ModeDecimal, want: "10", wantResult: floatPtr(10)},
+		{name: "рациональная степень", expression: "10^400", mode: models.ModeRational, want: huge},
+		{name: "рациональная дробь", expression: "-" + huge + " / 3", mode: models.ModeRational, want: "-" + huge + "/3"},
+		{name: "рациональная дробь меньше float64", expression: "1 / " + huge, mode: models.ModeRational, want: "1/" + huge, wantResult: floatPtr(0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm := NewTaskManager()
+			id, err := tm.CreateExpression(models.CalculateRequest{Expression: tt.expression, Mode: tt.mode})
+			if err != nil {
+				t.Fatalf("CreateExpression() error = %v", err)
+			}
+			for {
+				task, ok := tm.GetNextTask()
+				if !ok {
+					break
+				}
+				result := EvaluateTask(*task)
+				if _, err := json.Marshal(result); err != nil {
+					t.Fatalf("json.Marshal(result) error = %v", err)
+				}
+				if err := tm.UpdateTaskResult(result); err != nil {
+					t.Fatalf("UpdateTaskResult() error = %v", err)
+				}
+			}
+
+			expr, _ := tm.GetExpression(id)
+			if expr.Status != models.StatusCompleted || expr.Value != tt.want {
+				t.Fatalf("Expression = %+v, want completed with value %s", expr, tt.want)
+			}
+			if tt.mode == models.ModeRational && expr.Fraction == nil {
+				t.Errorf("Expression = %+v, want fraction", expr)
+			}
+			if (expr.Result == nil) != (tt.wantResult == nil) || (expr.Result != nil && *expr.Result != *tt.wantResult) {
+				t.Errorf("Expression result = %v, want %v", expr.Result, tt.wantResult)
+			}
+			if _, err := json.Marshal(tm.GetAllExpressions()); err != nil {
+				t.Errorf("json.Marshal(expressions) error = %v", err)
+			}
+		})
+	}
+
+	// в режиме float такой литерал по-прежнему отклоняется при разборе
+	if _, err := ParseExpression(huge); !errors.Is(err, ErrInvalidExpression) {
+		t.Errorf("ParseExpression() error = %v, want invalid number", err)
+	}
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestTaskManager_UnsupportedMode(t *testing.T) {
+	tm := NewTaskManager()
+	if _, err := tm.CreateExpression(models.CalculateRequest{Expression: "1+1", Mode: "complex"}); err == nil {
+		t.Errorf("CreateExpression() accepted unsupported mode")
+	}
+	if _, err := tm.CreateExpression(models.CalculateRequest{Expression: "1+1", Mode: models.ModeDecimal, Scale: -1}); err == nil {
+		t.Errorf("CreateExpression() accepted negative scale")
+	}
+}
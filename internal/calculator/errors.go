@@ -0,0 +1,69 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+)
+
+// код ошибки разбора выражения
+type ParseErrorCode string
+
+const (
+	CodeEmptyExpression    ParseErrorCode = "empty_expression"
+	CodeUnexpectedChar     ParseErrorCode = "unexpected_character"
+	CodeUnexpectedToken    ParseErrorCode = "unexpected_token"
+	CodeUnexpectedEnd      ParseErrorCode = "unexpected_end"
+	CodeMissingOperand     ParseErrorCode = "missing_operand"
+	CodeUnbalancedParens   ParseErrorCode = "unbalanced_parentheses"
+	CodeInvalidNumber      ParseErrorCode = "invalid_number"
+	CodeUnknownFunction    ParseErrorCode = "unknown_function"
+	CodeWrongArgumentCount ParseErrorCode = "wrong_argument_count"
+	CodeUnboundVariable    ParseErrorCode = "unbound_variable"
+)
+
+// ParseError описывает ошибку в тексте выражения.
+// Pos — смещение в байтах от начала исходной строки.
+type ParseError struct {
+	Code    ParseErrorCode
+	Message string
+	Pos     int
+	Token   string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Message, e.Pos)
+}
+
+// Unwrap позволяет проверять ошибку через errors.Is(err, ErrInvalidExpression)
+func (e *ParseError) Unwrap() error {
+	if e.Code == CodeUnboundVariable {
+		return ErrUnboundVariable
+	}
+	return ErrInvalidExpression
+}
+
+func newParseError(code ParseErrorCode, token Token, format string, args ...interface{}) *ParseError {
+	return &ParseError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+		Pos:     token.Pos,
+		Token:   token.Value,
+	}
+}
+
+// NewErrorResponse формирует тело ответа API для ошибки создания выражения
+func NewErrorResponse(err error) models.ErrorResponse {
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		pos := parseErr.Pos
+		return models.ErrorResponse{
+			Error:    parseErr.Message,
+			Code:     string(parseErr.Code),
+			Position: &pos,
+			Token:    parseErr.Token,
+		}
+	}
+	return models.ErrorResponse{Error: err.Error()}
+}
@@ -0,0 +1,80 @@
+package calculator
+
+import (
+	"fmt"
+)
+
+func applyOperation(ar Arithmetic, op string, args []Value) (Value, error) {
+	spec, ok := LookupOperation(op)
+	if !ok {
+		return nil, fmt.Errorf("unknown operation: %s", op)
+	}
+	if err := checkArity(op, len(args)); err != nil {
+		return nil, err
+	}
+	return spec.Eval(ar, args)
+}
+
+// nodeOperation возвращает имя операции узла в реестре
+func nodeOperation(node *Node) string {
+	if node.Token.Type == UnaryOperator {
+		if spec, ok := lookupOperator(PrefixOperator, node.Token.Value); ok {
+			return spec.Name
+		}
+	}
+	return node.Token.Value
+}
+
+// EvalAST рекурсивно вычисляет дерево выражения в заданной арифметике
+func EvalAST(ar Arithmetic, node *Node) (Value, error) {
+	if node == nil {
+		return nil, ErrInvalidExpression
+	}
+
+	switch node.Token.Type {
+	case Number:
+		return ar.Parse(node.Token.Value)
+	case Identifier:
+		return nil, newParseError(CodeUnboundVariable, node.Token, "unbound variable: %s", node.Token.Value)
+	case UnaryOperator, Operator, Function:
+	default:
+		return nil, ErrInvalidExpression
+	}
+
+	children := nodeOperands(node)
+	args := make([]Value, len(children))
+	for i, child := range children {
+		val, err := EvalAST(ar, child)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = val
+	}
+	return applyOperation(ar, nodeOperation(node), args)
+}
+
+// nodeOperands возвращает операнды узла в порядке их следования в выражении
+func nodeOperands(node *Node) []*Node {
+	switch node.Token.Type {
+	case UnaryOperator:
+		return []*Node{node.Left}
+	case Operator:
+		return []*Node{node.Left, node.Right}
+	default:
+		return node.Args
+	}
+}
+
+// Calc синхронно вычисляет выражение в float64 без участия агентов
+func Calc(expression string) (float64, error) {
+	node, err := ParseExpression(expression)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := EvalAST(FloatArithmetic{}, node)
+	if err != nil {
+		return 0, err
+	}
+	return result.Float64(), nil
+}
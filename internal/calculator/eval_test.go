@@ -0,0 +1,80 @@
+package calculator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+)
+
+// evalViaTasks вычисляет дерево так же, как распределённый путь: каждый узел
+// превращается в задачу со строковыми аргументами и считается через EvaluateTask
+func evalViaTasks(t *testing.T, node *Node, mode models.CalculationMode) string {
+	t.Helper()
+	ar, err := NewArithmetic(mode, 0)
+	if err != nil {
+		t.Fatalf("NewArithmetic() error = %v", err)
+	}
+
+	if node.Token.Type == Number {
+		val, err := ar.Parse(node.Token.Value)
+		if err != nil {
+			t.Fatalf("Parse() error = %v", err)
+		}
+		return ar.Format(val)
+	}
+
+	task := models.Task{ID: "1", Operation: nodeOperation(node), Mode: mode}
+	switch node.Token.Type {
+	case UnaryOperator:
+		task.Arg1 = evalViaTasks(t, node.Left, mode)
+	case Operator:
+		task.Arg1 = evalViaTasks(t, node.Left, mode)
+		task.Arg2 = evalViaTasks(t, node.Right, mode)
+	default:
+		for _, arg := range node.Args {
+			task.Args = append(task.Args, evalViaTasks(t, arg, mode))
+		}
+	}
+
+	result := EvaluateTask(task)
+	if result.Error != nil {
+		t.Fatalf("EvaluateTask(%+v) error = %s", task, *result.Error)
+	}
+	if mode == models.ModeFloat {
+		return ar.Format(floatNumber(result.Result))
+	}
+	return result.Value
+}
+
+func TestEvalAST_AgreesWithTasks(t *testing.T) {
+	expressions := []string{
+		"2+2*2",
+		"(1+2)*(3-4)/5",
+		"-(2^3)^2",
+		"2^3^2",
+		"max(1, -2, 3/4) + min(5, 6)",
+		"abs(-7/2) * sqrt(9/4)",
+		"0.1+0.2",
+	}
+	modes := []models.CalculationMode{models.ModeFloat, models.ModeDecimal, models.ModeRational}
+
+	for _, mode := range modes {
+		for _, expr := range expressions {
+			t.Run(fmt.Sprintf("%s %s", mode, expr), func(t *testing.T) {
+				node, err := ParseExpression(expr)
+				if err != nil {
+					t.Fatalf("ParseExpression() error = %v", err)
+				}
+				ar, _ := NewArithmetic(mode, 0)
+				local, err := EvalAST(ar, node)
+				if err != nil {
+					t.Fatalf("EvalAST() error = %v", err)
+				}
+				if got, want := ar.Format(local), evalViaTasks(t, node, mode); got != want {
+					t.Errorf("EvalAST() = %s, tasks = %s", got, want)
+				}
+			})
+		}
+	}
+}
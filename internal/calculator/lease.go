@@ -0,0 +1,162 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+)
+
+var (
+	ErrLeaseExpired  = errors.New("task lease expired")
+	ErrLeaseMismatch = errors.New("task is leased to another agent")
+	ErrLeaseRequired = errors.New("task is leased, result must carry its lease_id")
+)
+
+// DefaultLeaseGrace — запас сверх OperationTime, после которого задача выдаётся снова
+const DefaultLeaseGrace = 5 * time.Second
+
+// leaseGraceFromEnv читает запас аренды из TASK_LEASE_GRACE_MS
+func leaseGraceFromEnv() time.Duration {
+	if val := os.Getenv("TASK_LEASE_GRACE_MS"); val != "" {
+		if ms, err := strconv.ParseInt(val, 10, 64); err == nil && ms >= 0 {
+			return time.Duration(ms) * time.Millisecond
+		}
+	}
+	return DefaultLeaseGrace
+}
+
+// issueLease выдаёт аренду на задачу агенту agentID (пустой у анонимных
+// агентов и внутреннего воркера). Вызывается под tm.mu.
+func (tm *TaskManager) issueLease(task *models.Task, agentID string) {
+	tm.attempts[task.ID]++
+	now := tm.now()
+	lease := models.TaskLease{
+		TaskID:       task.ID,
+		LeaseID:      fmt.Sprintf("%s-%d", task.ID, tm.attempts[task.ID]),
+		ExpressionID: task.ExpressionID,
+		AgentID:      agentID,
+		Operation:    task.Operation,
+		Attempt:      tm.attempts[task.ID],
+		IssuedAt:     now,
+		Deadline:     now.Add(time.Duration(task.OperationTime)*time.Millisecond + tm.leaseGrace),
+	}
+	tm.leases[task.ID] = lease
+	task.LeaseID = lease.LeaseID
+}
+
+// reapExpiredLeases возвращает в начало очереди задачи с истёкшей арендой.
+// Вызывается под tm.mu.
+func (tm *TaskManager) reapExpiredLeases() {
+	now := tm.now()
+	var expired []models.TaskLease
+	for _, lease := range tm.leases {
+		if now.After(lease.Deadline) {
+			expired = append(expired, lease)
+		}
+	}
+	tm.requeueLeases(expired, "expired")
+}
+
+// requeueLeases снимает аренды и возвращает их задачи в начало очереди;
+// задачи, выданные раньше, раньше и возвращаются. Вызывается под tm.mu.
+func (tm *TaskManager) requeueLeases(leases []models.TaskLease, reason string) []string {
+	sort.Slice(leases, func(i, j int) bool { return leases[i].IssuedAt.Before(leases[j].IssuedAt) })
+
+	requeued := make([]string, 0, len(leases))
+	for _, lease := range leases {
+		delete(tm.leases, lease.TaskID)
+		log.Printf("Lease %s for task %s (ExprID: %s) %s, requeueing", lease.LeaseID, lease.TaskID, lease.ExpressionID, reason)
+		requeued = append(requeued, lease.TaskID)
+	}
+	if len(requeued) > 0 {
+		tm.ready = append(append([]string(nil), requeued...), tm.ready...)
+		tm.notifyReady()
+	}
+	return requeued
+}
+
+// nextLeaseDeadline возвращает ближайший срок аренды: после него задача
+// вернётся в очередь. Вызывается под tm.mu.
+func (tm *TaskManager) nextLeaseDeadline() (time.Time, bool) {
+	var next time.Time
+	for _, lease := range tm.leases {
+		if next.IsZero() || lease.Deadline.Before(next) {
+			next = lease.Deadline
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// ReleaseAgentTasks возвращает в очередь задачи, арендованные агентом, и
+// возвращает их ID. Поздние результаты агента по этим арендам отклоняются.
+func (tm *TaskManager) ReleaseAgentTasks(agentID string) []string {
+	if agentID == "" {
+		return nil
+	}
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	var held []models.TaskLease
+	for _, lease := range tm.leases {
+		if lease.AgentID == agentID {
+			held = append(held, lease)
+		}
+	}
+	return tm.requeueLeases(held, "released by agent "+agentID)
+}
+
+// checkLease проверяет, что результат пришёл от текущего держателя аренды,
+// а при непустом agentID — что аренда выдана этому агенту. Пока аренда
+// действует, результат без её LeaseID отклоняется. Вызывается под tm.mu.
+func (tm *TaskManager) checkLease(taskID, leaseID, agentID string) error {
+	lease, active := tm.leases[taskID]
+	if !active {
+		if leaseID != "" {
+			return fmt.Errorf("%w: %s", ErrLeaseExpired, leaseID)
+		}
+		return nil
+	}
+	if agentID != "" && lease.AgentID != agentID {
+		return fmt.Errorf("%w: %s", ErrLeaseMismatch, lease.LeaseID)
+	}
+	if leaseID == "" {
+		return fmt.Errorf("%w: %s", ErrLeaseRequired, taskID)
+	}
+	if lease.LeaseID != leaseID {
+		return fmt.Errorf("%w: %s", ErrLeaseMismatch, leaseID)
+	}
+	return nil
+}
+
+// releaseTask снимает аренду и убирает задачу из очереди, если она туда вернулась.
+// Вызывается под tm.mu.
+func (tm *TaskManager) releaseTask(taskID string) {
+	delete(tm.leases, taskID)
+	delete(tm.attempts, taskID)
+	for i, id := range tm.ready {
+		if id == taskID {
+			tm.ready = append(tm.ready[:i], tm.ready[i+1:]...)
+			break
+		}
+	}
+}
+
+// Leases возвращает активные аренды в порядке истечения
+func (tm *TaskManager) Leases() []models.TaskLease {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	tm.reapExpiredLeases()
+	leases := make([]models.TaskLease, 0, len(tm.leases))
+	for _, lease := range tm.leases {
+		leases = append(leases, lease)
+	}
+	sort.Slice(leases, func(i, j int) bool { return leases[i].Deadline.Before(leases[j].Deadline) })
+	return leases
+}
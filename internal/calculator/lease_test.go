@@ -0,0 +1,159 @@
+package calculator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+)
+
+// newTestTaskManager возвращает менеджер с управляемыми часами
+func newTestTaskManager(t *testing.T, expression string) (*TaskManager, *time.Time) {
+	t.Helper()
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tm := NewTaskManager()
+	tm.leaseGrace = time.Second
+	tm.now = func() time.Time { return clock }
+	if _, err := tm.CreateExpression(models.CalculateRequest{Expression: expression}); err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+	return tm, &clock
+}
+
+func TestTaskManager_LeaseExpiry(t *testing.T) {
+	tm, clock := newTestTaskManager(t, "2+2")
+
+	first, ok := tm.GetNextTask()
+	if !ok {
+		t.Fatalf("GetNextTask() returned no task")
+	}
+	if first.LeaseID == "" {
+		t.Fatalf("GetNextTask() returned a task without a lease")
+	}
+	if _, ok := tm.GetNextTask(); ok {
+		t.Fatalf("GetNextTask() returned a leased task again before the deadline")
+	}
+
+	leases := tm.Leases()
+	if len(leases) != 1 || leases[0].TaskID != first.ID || leases[0].Attempt != 1 {
+		t.Fatalf("Leases() = %+v, want one lease for task %s", leases, first.ID)
+	}
+	wantDeadline := clock.Add(time.Duration(first.OperationTime)*time.Millisecond + time.Second)
+	if !leases[0].Deadline.Equal(wantDeadline) {
+		t.Errorf("Lease deadline = %v, want %v", leases[0].Deadline, wantDeadline)
+	}
+
+	*clock = wantDeadline.Add(time.Millisecond)
+	second, ok := tm.GetNextTask()
+	if !ok || second.ID != first.ID {
+		t.Fatalf("GetNextTask() = %+v, want task %s re-dispatched", second, first.ID)
+	}
+	if second.LeaseID == first.LeaseID {
+		t.Fatalf("re-dispatched task kept lease %s", first.LeaseID)
+	}
+
+	stale := EvaluateTask(*first)
+	if err := tm.UpdateTaskResult(stale); !errors.Is(err, ErrLeaseMismatch) {
+		t.Errorf("UpdateTaskResult() with stale lease error = %v, want %v", err, ErrLeaseMismatch)
+	}
+	if err := tm.UpdateTaskResult(EvaluateTask(*second)); err != nil {
+		t.Fatalf("UpdateTaskResult() error = %v", err)
+	}
+	if leases := tm.Leases(); len(leases) != 0 {
+		t.Errorf("Leases() = %+v, want none after completion", leases)
+	}
+}
+
+func TestTaskManager_LateResultRejected(t *testing.T) {
+	tm, clock := newTestTaskManager(t, "2+2")
+
+	task, _ := tm.GetNextTask()
+	*clock = clock.Add(time.Hour)
+
+	if err := tm.UpdateTaskResult(EvaluateTask(*task)); !errors.Is(err, ErrLeaseExpired) {
+		t.Fatalf("UpdateTaskResult() error = %v, want %v", err, ErrLeaseExpired)
+	}
+
+	again, ok := tm.GetNextTask()
+	if !ok || again.ID != task.ID {
+		t.Fatalf("GetNextTask() = %+v, want expired task %s", again, task.ID)
+	}
+}
+
+func TestTaskManager_ResultWithoutLease(t *testing.T) {
+	tm, clock := newTestTaskManager(t, "2+2")
+
+	task, _ := tm.GetNextTask()
+	if err := tm.UpdateTaskResult(models.TaskResult{ID: task.ID, Result: 4}); !errors.Is(err, ErrLeaseRequired) {
+		t.Errorf("UpdateTaskResult() without lease error = %v, want %v", err, ErrLeaseRequired)
+	}
+
+	// аренда истекла и задача выдана снова: прежний агент не обойдёт
+	// отказ, не передав LeaseID
+	*clock = clock.Add(time.Hour)
+	again, ok := tm.GetNextTask()
+	if !ok || again.ID != task.ID {
+		t.Fatalf("GetNextTask() = %+v, want task %s re-dispatched", again, task.ID)
+	}
+	if err := tm.UpdateTaskResult(models.TaskResult{ID: task.ID, Result: 5}); !errors.Is(err, ErrLeaseRequired) {
+		t.Errorf("UpdateTaskResult() without lease error = %v, want %v", err, ErrLeaseRequired)
+	}
+	if err := tm.UpdateTaskResult(EvaluateTask(*again)); err != nil {
+		t.Fatalf("UpdateTaskResult() error = %v", err)
+	}
+	expr := tm.GetAllExpressions()[0]
+	if expr.Result == nil || *expr.Result != 4 {
+		t.Errorf("Expression = %+v, want result of the current lease holder", expr)
+	}
+}
+
+func TestTaskManager_ReleaseAgentTasks(t *testing.T) {
+	tm, _ := newTestTaskManager(t, "(1+2)*(3+4)")
+
+	held, _ := tm.GetNextTaskForAgent("agent-1", SupportedModes())
+	other, _ := tm.GetNextTaskForAgent("agent-2", SupportedModes())
+	if held == nil || other == nil {
+		t.Fatalf("GetNextTaskForAgent() returned no task")
+	}
+	if leases := tm.Leases(); len(leases) != 2 || leases[0].AgentID == "" {
+		t.Fatalf("Leases() = %+v, want leases recorded for agents", leases)
+	}
+
+	released := tm.ReleaseAgentTasks("agent-1")
+	if len(released) != 1 || released[0] != held.ID {
+		t.Fatalf("ReleaseAgentTasks() = %v, want [%s]", released, held.ID)
+	}
+	if released := tm.ReleaseAgentTasks(""); len(released) != 0 {
+		t.Errorf("ReleaseAgentTasks(\"\") = %v, want nothing", released)
+	}
+
+	// задача исключённого агента выдаётся снова, его поздний результат отклоняется
+	again, ok := tm.GetNextTaskForAgent("agent-3", SupportedModes())
+	if !ok || again.ID != held.ID {
+		t.Fatalf("GetNextTaskForAgent() = %+v, want task %s requeued", again, held.ID)
+	}
+	if err := tm.UpdateTaskResult(EvaluateTask(*held)); !errors.Is(err, ErrLeaseMismatch) {
+		t.Errorf("UpdateTaskResult() from released agent error = %v, want %v", err, ErrLeaseMismatch)
+	}
+	if err := tm.UpdateTaskResult(EvaluateTask(*other)); err != nil {
+		t.Errorf("UpdateTaskResult() from another agent error = %v", err)
+	}
+}
+
+func TestTaskManager_ResultFromLeaseHolder(t *testing.T) {
+	tm, _ := newTestTaskManager(t, "2+2")
+
+	task, _ := tm.GetNextTaskForAgent("agent-1", SupportedModes())
+	if task == nil {
+		t.Fatalf("GetNextTaskForAgent() returned no task")
+	}
+
+	// LeaseID известен, но аренда выдана другому агенту
+	if err := tm.UpdateTaskResultForAgent("agent-2", EvaluateTask(*task)); !errors.Is(err, ErrLeaseMismatch) {
+		t.Errorf("UpdateTaskResultForAgent() from another agent error = %v, want %v", err, ErrLeaseMismatch)
+	}
+	if err := tm.UpdateTaskResultForAgent("agent-1", EvaluateTask(*task)); err != nil {
+		t.Errorf("UpdateTaskResultForAgent() from lease holder error = %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+)
+
+var (
+	ErrUnsupportedMode = errors.New("unsupported calculation mode")
+	ErrInexactResult   = errors.New("result cannot be represented exactly")
+)
+
+// Value — число в одном из режимов вычисления
+type Value interface {
+	Float64() float64
+}
+
+// Arithmetic реализует операции над числами конкретного режима вычисления.
+// Значения передаются между оркестратором и агентами строками через Parse и Format.
+type Arithmetic interface {
+	Mode() models.CalculationMode
+	Parse(s string) (Value, error)
+	Format(n Value) string
+	FromFloat(f float64) (Value, error)
+	Add(a, b Value) (Value, error)
+	Sub(a, b Value) (Value, error)
+	Mul(a, b Value) (Value, error)
+	Div(a, b Value) (Value, error)
+	Neg(a Value) (Value, error)
+	Pow(a, b Value) (Value, error)
+	Sqrt(a Value) (Value, error)
+	Cmp(a, b Value) int
+}
+
+// SupportedModes перечисляет режимы, которые умеет вычислять этот пакет
+func SupportedModes() []models.CalculationMode {
+	return []models.CalculationMode{models.ModeFloat, models.ModeDecimal, models.ModeRational}
+}
+
+// NewArithmetic возвращает арифметику для режима; пустой режим означает float
+func NewArithmetic(mode models.CalculationMode, scale int) (Arithmetic, error) {
+	switch mode {
+	case "", models.ModeFloat:
+		return FloatArithmetic{}, nil
+	case models.ModeDecimal:
+		if scale <= 0 {
+			scale = DefaultDecimalScale
+		}
+		return DecimalArithmetic{Scale: scale}, nil
+	case models.ModeRational:
+		return RationalArithmetic{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedMode, mode)
+	}
+}
+
+// TaskArgs возвращает аргументы задачи в порядке их следования в выражении
+func TaskArgs(task models.Task) []string {
+	spec, ok := LookupOperation(task.Operation)
+	switch {
+	case ok && spec.Kind == PrefixOperator:
+		return []string{task.Arg1}
+	case ok && spec.Kind == FunctionCall:
+		return task.Args
+	default:
+		return []string{task.Arg1, task.Arg2}
+	}
+}
+
+// Evaluate разбирает аргументы и выполняет над ними операцию или функцию
+func Evaluate(ar Arithmetic, op string, args []string) (Value, error) {
+	values := make([]Value, len(args))
+	for i, arg := range args {
+		val, err := ar.Parse(arg)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = val
+	}
+	return applyOperation(ar, op, values)
+}
+
+// EvaluateTask вычисляет задачу в её режиме. В точных режимах результат
+// возвращается строкой в поле Value, а в Result кладётся его приближение.
+func EvaluateTask(task models.Task) models.TaskResult {
+	taskResult := models.TaskResult{ID: task.ID, LeaseID: task.LeaseID}
+
+	ar, err := NewArithmetic(task.Mode, task.Scale)
+	if err == nil {
+		var result Value
+		if result, err = Evaluate(ar, task.Operation, TaskArgs(task)); err == nil {
+			// точное значение вне диапазона float64 передаётся только в Value
+			if f := result.Float64(); !math.IsInf(f, 0) {
+				taskResult.Result = f
+			}
+			if ar.Mode() != models.ModeFloat {
+				taskResult.Value = ar.Format(result)
+			}
+			return taskResult
+		}
+	}
+
+	errMsg := err.Error()
+	taskResult.Error = &errMsg
+	return taskResult
+}
+
+type floatNumber float64
+
+func (n floatNumber) Float64() float64 { return float64(n) }
+
+// FloatArithmetic — вычисления в float64
+type FloatArithmetic struct{}
+
+func (FloatArithmetic) Mode() models.CalculationMode { return models.ModeFloat }
+
+func (FloatArithmetic) Parse(s string) (Value, error) {
+	val, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number: %q", s)
+	}
+	return floatNumber(val), nil
+}
+
+func (FloatArithmetic) Format(n Value) string {
+	return strconv.FormatFloat(n.Float64(), 'g', -1, 64)
+}
+
+func (FloatArithmetic) FromFloat(f float64) (Value, error) { return floatResult(f) }
+
+// floatResult отклоняет бесконечность и NaN: их нельзя передать в JSON
+func floatResult(f float64) (Value, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, fmt.Errorf("result is out of range: %g", f)
+	}
+	return floatNumber(f), nil
+}
+
+func (FloatArithmetic) Add(a, b Value) (Value, error) {
+	return floatResult(a.Float64() + b.Float64())
+}
+
+func (FloatArithmetic) Sub(a, b Value) (Value, error) {
+	return floatResult(a.Float64() - b.Float64())
+}
+
+func (FloatArithmetic) Mul(a, b Value) (Value, error) {
+	return floatResult(a.Float64() * b.Float64())
+}
+
+func (FloatArithmetic) Div(a, b Value) (Value, error) {
+	if b.Float64() == 0 {
+		return nil, fmt.Errorf("division by zero")
+	}
+	return floatResult(a.Float64() / b.Float64())
+}
+
+func (FloatArithmetic) Neg(a Value) (Value, error) { return floatNumber(-a.Float64()), nil }
+
+func (FloatArithmetic) Pow(a, b Value) (Value, error) {
+	result, err := power(a.Float64(), b.Float64())
+	if err != nil {
+		return nil, err
+	}
+	return floatNumber(result), nil
+}
+
+func (FloatArithmetic) Sqrt(a Value) (Value, error) {
+	if a.Float64() < 0 {
+		return nil, fmt.Errorf("square root of negative number: %g", a.Float64())
+	}
+	return floatNumber(math.Sqrt(a.Float64())), nil
+}
+
+func (FloatArithmetic) Cmp(a, b Value) int {
+	switch x, y := a.Float64(), b.Float64(); {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
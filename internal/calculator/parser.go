@@ -1,14 +1,20 @@
 package calculator
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
+
+	"github.com/superlogarifm/goCalc-v3/internal/models"
 )
 
 var (
 	ErrInvalidExpression = fmt.Errorf("invalid expression")
+	ErrUnboundVariable   = fmt.Errorf("unbound variable")
 )
 
 type TokenType int
@@ -18,16 +24,28 @@ const (
 	Operator
 	LeftParen
 	RightParen
+	UnaryOperator
+	Identifier
+	Comma
+	Function // узел вызова функции в AST
 )
 
+// операция унарного минуса в задачах
+const OpNegate = "neg"
+
+// операция возведения в степень
+const OpPower = "^"
+
 type Token struct {
 	Type  TokenType
 	Value string
+	Pos   int // смещение токена в исходной строке в байтах
 }
 
 type Node struct {
 	Left     *Node
 	Right    *Node
+	Args     []*Node // аргументы вызова функции
 	Token    Token
 	TaskID   string
 	Computed bool
@@ -35,114 +53,270 @@ type Node struct {
 }
 
 func tokenize(expr string) ([]Token, error) {
-	expr = strings.ReplaceAll(expr, " ", "")
-
 	var tokens []Token
-	var current strings.Builder
 
 	for i := 0; i < len(expr); i++ {
 		ch := rune(expr[i])
 
+		if unicode.IsSpace(ch) {
+			continue
+		}
+
 		if unicode.IsDigit(ch) || ch == '.' {
-			current.WriteRune(ch)
+			start := i
+			for i+1 < len(expr) && (unicode.IsDigit(rune(expr[i+1])) || expr[i+1] == '.') {
+				i++
+			}
+			tokens = append(tokens, Token{Type: Number, Value: expr[start : i+1], Pos: start})
 			continue
 		}
 
-		if current.Len() > 0 {
-			tokens = append(tokens, Token{Type: Number, Value: current.String()})
-			current.Reset()
+		if isIdentifierStart(ch) {
+			start := i
+			for i+1 < len(expr) && isIdentifierPart(rune(expr[i+1])) {
+				i++
+			}
+			tokens = append(tokens, Token{Type: Identifier, Value: expr[start : i+1], Pos: start})
+			continue
+		}
+
+		token := Token{Value: string(ch), Pos: i}
+		if symbol := matchOperatorSymbol(expr[i:]); symbol != "" {
+			token.Value = symbol
+			i += len(symbol) - 1
+			if expectsOperand(tokens) {
+				if _, ok := lookupOperator(PrefixOperator, symbol); !ok {
+					return nil, newParseError(CodeMissingOperand, token, "missing operand before %s", symbol)
+				}
+				token.Type = UnaryOperator
+			} else {
+				spec, ok := lookupOperator(BinaryOperator, symbol)
+				if !ok {
+					return nil, newParseError(CodeUnexpectedToken, token, "unexpected operator: %s", symbol)
+				}
+				// синонимы вроде ** приводятся к имени операции
+				token.Type, token.Value = Operator, spec.Name
+			}
+			tokens = append(tokens, token)
+			continue
 		}
 
 		switch ch {
-		case '+', '-', '*', '/':
-			tokens = append(tokens, Token{Type: Operator, Value: string(ch)})
 		case '(':
-			tokens = append(tokens, Token{Type: LeftParen})
+			token.Type = LeftParen
 		case ')':
-			tokens = append(tokens, Token{Type: RightParen})
+			token.Type = RightParen
+		case ',':
+			token.Type = Comma
+			if expectsOperand(tokens) {
+				return nil, newParseError(CodeMissingOperand, token, "missing argument before ,")
+			}
 		default:
-			return nil, fmt.Errorf("unexpected character: %c", ch)
+			r, _ := utf8.DecodeRuneInString(expr[i:])
+			token.Value = string(r)
+			return nil, newParseError(CodeUnexpectedChar, token, "unexpected character: %c", r)
 		}
+		tokens = append(tokens, token)
 	}
 
-	if current.Len() > 0 {
-		tokens = append(tokens, Token{Type: Number, Value: current.String()})
+	if len(tokens) == 0 {
+		return nil, newParseError(CodeEmptyExpression, Token{Pos: len(expr)}, "empty expression")
 	}
 
+	if expectsOperand(tokens) {
+		return nil, newParseError(CodeUnexpectedEnd, Token{Pos: len(expr)}, "unexpected end of expression")
+	}
+
+	return tokens, nil
+}
+
+// expectsOperand сообщает, что следующим токеном должен быть операнд,
+// то есть знак + или - в этой позиции является унарным
+func expectsOperand(tokens []Token) bool {
 	if len(tokens) == 0 {
-		return nil, ErrInvalidExpression
+		return true
 	}
+	switch tokens[len(tokens)-1].Type {
+	case Operator, UnaryOperator, LeftParen, Comma:
+		return true
+	}
+	return false
+}
 
-	if tokens[0].Type == Operator || tokens[len(tokens)-1].Type == Operator {
-		return nil, ErrInvalidExpression
+func isIdentifierStart(ch rune) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isIdentifierPart(ch rune) bool {
+	return isIdentifierStart(ch) || unicode.IsDigit(ch)
+}
+
+// tokenPrecedence возвращает приоритет оператора из реестра
+func tokenPrecedence(token Token) int {
+	if spec, ok := tokenOperator(token); ok {
+		return spec.Precedence
 	}
+	return 0
+}
 
-	return tokens, nil
+func tokenOperator(token Token) (*OperationSpec, bool) {
+	switch token.Type {
+	case UnaryOperator:
+		return lookupOperator(PrefixOperator, token.Value)
+	case Operator:
+		// бинарный токен уже приведён к имени операции, а не к записи
+		if spec, ok := LookupOperation(token.Value); ok && spec.Kind == BinaryOperator {
+			return spec, true
+		}
+	}
+	return nil, false
 }
 
-func getPrecedence(op string) int {
-	switch op {
-	case "+", "-":
-		return 1
-	case "*", "/":
-		return 2
+// applyOperator снимает со стека операндов аргументы оператора и кладёт на него узел
+func applyOperator(output []*Node, op *Node) ([]*Node, error) {
+	switch op.Token.Type {
+	case UnaryOperator:
+		if len(output) < 1 {
+			return nil, newParseError(CodeMissingOperand, op.Token, "missing operand for %s", op.Token.Value)
+		}
+		operand := output[len(output)-1]
+		output = output[:len(output)-1]
+		return append(output, applyUnary(op, operand)), nil
+	case Operator:
+		if len(output) < 2 {
+			return nil, newParseError(CodeMissingOperand, op.Token, "missing operand for %s", op.Token.Value)
+		}
+		op.Right = output[len(output)-1]
+		op.Left = output[len(output)-2]
+		output = output[:len(output)-2]
+		return append(output, op), nil
+	case LeftParen:
+		return nil, newParseError(CodeUnbalancedParens, op.Token, "unclosed parenthesis")
 	default:
-		return 0
+		return nil, newParseError(CodeUnexpectedToken, op.Token, "unexpected token: %s", op.Token.Value)
+	}
+}
+
+// applyUnary строит узел унарного оператора. Унарный плюс отбрасывается,
+// а минус перед числом сворачивается в отрицательный литерал.
+func applyUnary(op *Node, operand *Node) *Node {
+	switch nodeOperation(op) {
+	case OpPlus:
+		return operand
+	case OpNegate:
+		if operand.Token.Type == Number {
+			literal := &Node{Token: Token{Type: Number, Value: negateLiteral(operand.Token.Value), Pos: op.Token.Pos}}
+			if operand.Result != nil {
+				val := -*operand.Result
+				literal.Result = &val
+			}
+			return literal
+		}
 	}
+	op.Left = operand
+	return op
 }
 
-func buildAST(tokens []Token) (*Node, error) {
+func negateLiteral(value string) string {
+	if strings.HasPrefix(value, "-") {
+		return value[1:]
+	}
+	return "-" + value
+}
+
+// parenFrame описывает открытую скобку: обычную или скобку вызова функции
+type parenFrame struct {
+	call      bool
+	outputLen int
+	commas    int
+}
+
+// buildAST строит дерево из токенов. В точных режимах (exact) допускаются
+// литералы вне диапазона float64: у их узлов нет Result.
+func buildAST(tokens []Token, exact bool) (*Node, error) {
 	var output []*Node
 	var operators []*Node
+	var frames []parenFrame
+	var err error
 
-	for _, token := range tokens {
+	for i, token := range tokens {
 		switch token.Type {
 		case Number:
 			val, err := strconv.ParseFloat(token.Value, 64)
 			if err != nil {
-				return nil, fmt.Errorf("invalid number: %s", token.Value)
+				if !exact || !errors.Is(err, strconv.ErrRange) {
+					return nil, newParseError(CodeInvalidNumber, token, "invalid number: %s", token.Value)
+				}
+				output = append(output, &Node{Token: token})
+				continue
 			}
 			output = append(output, &Node{Token: token, Result: &val})
 
+		case Identifier:
+			if i+1 >= len(tokens) || tokens[i+1].Type != LeftParen {
+				// переменная, значение подставляется в BindVariables
+				output = append(output, &Node{Token: token})
+				continue
+			}
+			if !IsFunction(token.Value) {
+				return nil, newParseError(CodeUnknownFunction, token, "unknown function: %s", token.Value)
+			}
+			operators = append(operators, &Node{Token: Token{Type: Function, Value: token.Value, Pos: token.Pos}})
+
+		case UnaryOperator:
+			operators = append(operators, &Node{Token: token})
+
 		case Operator:
-			node := &Node{Token: token}
 			for len(operators) > 0 {
 				top := operators[len(operators)-1]
 				if top.Token.Type == LeftParen {
 					break
 				}
-				if getPrecedence(top.Token.Value) >= getPrecedence(token.Value) {
-					operators = operators[:len(operators)-1]
-					right := output[len(output)-1]
-					output = output[:len(output)-1]
-					left := output[len(output)-1]
-					output = output[:len(output)-1]
-					top.Left = left
-					top.Right = right
-					output = append(output, top)
-				} else {
+				spec, _ := tokenOperator(token)
+				if topPrec := tokenPrecedence(top.Token); topPrec < spec.Precedence || (topPrec == spec.Precedence && spec.RightAssociative) {
 					break
 				}
+				operators = operators[:len(operators)-1]
+				if output, err = applyOperator(output, top); err != nil {
+					return nil, err
+				}
 			}
-			operators = append(operators, node)
+			operators = append(operators, &Node{Token: token})
 
 		case LeftParen:
+			call := len(operators) > 0 && operators[len(operators)-1].Token.Type == Function
+			frames = append(frames, parenFrame{call: call, outputLen: len(output)})
 			operators = append(operators, &Node{Token: token})
 
+		case Comma:
+			if len(frames) == 0 || !frames[len(frames)-1].call {
+				return nil, newParseError(CodeUnexpectedToken, token, "unexpected , outside of function call")
+			}
+			if operators, output, err = unwindToParen(operators, output, token); err != nil {
+				return nil, err
+			}
+			frames[len(frames)-1].commas++
+
 		case RightParen:
-			for len(operators) > 0 {
-				top := operators[len(operators)-1]
+			if len(frames) == 0 {
+				return nil, newParseError(CodeUnbalancedParens, token, "unmatched closing parenthesis")
+			}
+			if operators, output, err = unwindToParen(operators, output, token); err != nil {
+				return nil, err
+			}
+			operators = operators[:len(operators)-1]
+			frame := frames[len(frames)-1]
+			frames = frames[:len(frames)-1]
+
+			if !frame.call && len(output) == frame.outputLen {
+				return nil, newParseError(CodeMissingOperand, token, "empty parentheses")
+			}
+			if frame.call {
+				call := operators[len(operators)-1]
 				operators = operators[:len(operators)-1]
-				if top.Token.Type == LeftParen {
-					break
+				if output, err = applyCall(output, call, frame, token); err != nil {
+					return nil, err
 				}
-				right := output[len(output)-1]
-				output = output[:len(output)-1]
-				left := output[len(output)-1]
-				output = output[:len(output)-1]
-				top.Left = left
-				top.Right = right
-				output = append(output, top)
 			}
 		}
 	}
@@ -150,76 +324,112 @@ func buildAST(tokens []Token) (*Node, error) {
 	for len(operators) > 0 {
 		top := operators[len(operators)-1]
 		operators = operators[:len(operators)-1]
-		right := output[len(output)-1]
-		output = output[:len(output)-1]
-		left := output[len(output)-1]
-		output = output[:len(output)-1]
-		top.Left = left
-		top.Right = right
-		output = append(output, top)
+		if output, err = applyOperator(output, top); err != nil {
+			return nil, err
+		}
 	}
 
-	if len(output) != 1 {
-		return nil, fmt.Errorf("invalid expression")
+	if len(output) == 0 {
+		return nil, ErrInvalidExpression
+	}
+	if len(output) > 1 {
+		// два операнда подряд без оператора между ними
+		return nil, newParseError(CodeUnexpectedToken, output[1].Token, "unexpected operand: %s", output[1].Token.Value)
 	}
 
 	return output[0], nil
 }
 
+// unwindToParen применяет операторы до ближайшей открывающей скобки, не снимая её
+func unwindToParen(operators, output []*Node, closing Token) ([]*Node, []*Node, error) {
+	var err error
+	for len(operators) > 0 {
+		top := operators[len(operators)-1]
+		if top.Token.Type == LeftParen {
+			return operators, output, nil
+		}
+		operators = operators[:len(operators)-1]
+		if output, err = applyOperator(output, top); err != nil {
+			return nil, nil, err
+		}
+	}
+	return nil, nil, newParseError(CodeUnbalancedParens, closing, "unmatched %s", closing.Value)
+}
+
+// applyCall переносит аргументы вызова со стека операндов в узел функции
+func applyCall(output []*Node, call *Node, frame parenFrame, closing Token) ([]*Node, error) {
+	argc := len(output) - frame.outputLen
+	if argc > frame.commas+1 {
+		extra := output[frame.outputLen+frame.commas+1]
+		return nil, newParseError(CodeUnexpectedToken, extra.Token, "unexpected operand: %s", extra.Token.Value)
+	}
+	if argc != frame.commas+1 && !(argc == 0 && frame.commas == 0) {
+		return nil, newParseError(CodeMissingOperand, closing, "missing argument in call of %s", call.Token.Value)
+	}
+	if err := checkArity(call.Token.Value, argc); err != nil {
+		return nil, newParseError(CodeWrongArgumentCount, call.Token, "%v", err)
+	}
+	call.Args = append([]*Node(nil), output[frame.outputLen:]...)
+	return append(output[:frame.outputLen], call), nil
+}
+
+// power возводит base в степень exp, отклоняя результаты вне действительных чисел
+func power(base, exp float64) (float64, error) {
+	result := math.Pow(base, exp)
+	if math.IsNaN(result) {
+		return 0, fmt.Errorf("invalid power operation: %g^%g", base, exp)
+	}
+	if math.IsInf(result, 0) {
+		return 0, fmt.Errorf("power result is out of range: %g^%g", base, exp)
+	}
+	return result, nil
+}
+
 func ParseExpression(expr string) (*Node, error) {
+	return ParseExpressionFor(expr, models.ModeFloat)
+}
+
+// ParseExpressionFor разбирает выражение для режима mode
+func ParseExpressionFor(expr string, mode models.CalculationMode) (*Node, error) {
 	tokens, err := tokenize(expr)
 	if err != nil {
 		return nil, err
 	}
 
-	return buildAST(tokens)
+	return buildAST(tokens, mode == models.ModeDecimal || mode == models.ModeRational)
 }
 
-func Calc(expression string) (float64, error) {
-	node, err := ParseExpression(expression)
-	if err != nil {
-		return 0, err
+// BindVariables заменяет переменные в AST их значениями.
+// Возвращает ErrUnboundVariable с именем первой переменной без значения.
+func BindVariables(node *Node, variables map[string]float64) (*Node, error) {
+	if node == nil {
+		return nil, nil
 	}
 
-	if node.Token.Type == Number {
-		val, err := strconv.ParseFloat(node.Token.Value, 64)
-		if err != nil {
-			return 0, err
+	if node.Token.Type == Identifier {
+		val, ok := variables[node.Token.Value]
+		if !ok {
+			return nil, newParseError(CodeUnboundVariable, node.Token, "unbound variable: %s", node.Token.Value)
 		}
-		return val, nil
+		return &Node{Token: Token{Type: Number, Value: strconv.FormatFloat(val, 'f', -1, 64), Pos: node.Token.Pos}, Result: &val}, nil
 	}
 
-	if node.Token.Type == Operator {
-		if node.Left == nil || node.Right == nil {
-			return 0, ErrInvalidExpression
-		}
-
-		leftVal, err := Calc(node.Left.Token.Value)
-		if err != nil {
-			return 0, err
-		}
-
-		rightVal, err := Calc(node.Right.Token.Value)
-		if err != nil {
-			return 0, err
-		}
-
-		switch node.Token.Value {
-		case "+":
-			return leftVal + rightVal, nil
-		case "-":
-			return leftVal - rightVal, nil
-		case "*":
-			return leftVal * rightVal, nil
-		case "/":
-			if rightVal == 0 {
-				return 0, fmt.Errorf("division by zero")
-			}
-			return leftVal / rightVal, nil
-		default:
-			return 0, fmt.Errorf("unknown operator: %s", node.Token.Value)
+	var err error
+	if node.Left, err = BindVariables(node.Left, variables); err != nil {
+		return nil, err
+	}
+	if node.Right, err = BindVariables(node.Right, variables); err != nil {
+		return nil, err
+	}
+	for i, arg := range node.Args {
+		if node.Args[i], err = BindVariables(arg, variables); err != nil {
+			return nil, err
 		}
 	}
 
-	return 0, ErrInvalidExpression
+	if node.Token.Type == UnaryOperator {
+		// после подстановки минус перед переменной тоже сворачивается в литерал
+		return applyUnary(&Node{Token: node.Token}, node.Left), nil
+	}
+	return node, nil
 }
@@ -1,6 +1,7 @@
 package calculator
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -53,6 +54,36 @@ func TestTokenize(t *testing.T) {
 			wantLen: 0,
 			wantErr: true,
 		},
+		{
+			name:    "унарный минус в начале",
+			expr:    "-5+3",
+			wantLen: 4,
+			wantErr: false,
+		},
+		{
+			name:    "унарный минус после оператора",
+			expr:    "2*-3",
+			wantLen: 4,
+			wantErr: false,
+		},
+		{
+			name:    "степень через **",
+			expr:    "2**3",
+			wantLen: 3,
+			wantErr: false,
+		},
+		{
+			name:    "вызов функции",
+			expr:    "max(1, 2)",
+			wantLen: 6,
+			wantErr: false,
+		},
+		{
+			name:    "бинарный оператор в начале",
+			expr:    "*2",
+			wantLen: 0,
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -100,6 +131,66 @@ func TestParseExpression(t *testing.T) {
 			expr:    "2+",
 			wantErr: true,
 		},
+		{
+			name:    "унарный минус перед скобкой",
+			expr:    "-(1+2)",
+			wantErr: false,
+		},
+		{
+			name:    "двойной унарный минус",
+			expr:    "--5",
+			wantErr: false,
+		},
+		{
+			name:    "два бинарных оператора подряд",
+			expr:    "2+*3",
+			wantErr: true,
+		},
+		{
+			name:    "незакрытая скобка",
+			expr:    "(2+3",
+			wantErr: true,
+		},
+		{
+			name:    "лишняя закрывающая скобка",
+			expr:    "2+3)",
+			wantErr: true,
+		},
+		{
+			name:    "функция с выражением в аргументе",
+			expr:    "sqrt(2*8)+1",
+			wantErr: false,
+		},
+		{
+			name:    "вложенные вызовы функций",
+			expr:    "max(abs(-3), min(1, 2, 3), 2)",
+			wantErr: false,
+		},
+		{
+			name:    "неизвестная функция",
+			expr:    "foo(1)",
+			wantErr: true,
+		},
+		{
+			name:    "неверное число аргументов",
+			expr:    "sqrt(1, 2)",
+			wantErr: true,
+		},
+		{
+			name:    "вызов без аргументов",
+			expr:    "max()",
+			wantErr: true,
+		},
+		{
+			name:    "пустой аргумент",
+			expr:    "max(1,,2)",
+			wantErr: true,
+		},
+		{
+			name:    "запятая вне вызова",
+			expr:    "(1,2)",
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -167,6 +258,66 @@ func TestCalc(t *testing.T) {
 			want:    0,
 			wantErr: true,
 		},
+		{
+			name:    "отрицательное число",
+			expr:    "-5+3",
+			want:    -2,
+			wantErr: false,
+		},
+		{
+			name:    "умножение на отрицательное число",
+			expr:    "2*-3",
+			want:    -6,
+			wantErr: false,
+		},
+		{
+			name:    "степень",
+			expr:    "2^3",
+			want:    8,
+			wantErr: false,
+		},
+		{
+			name:    "степень через **",
+			expr:    "2**10",
+			want:    1024,
+			wantErr: false,
+		},
+		{
+			name:    "переполнение степени",
+			expr:    "10^400",
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "переполнение умножения",
+			expr:    "1e300*1e300",
+			want:    0,
+			wantErr: true,
+		},
+		{
+			name:    "вложенные скобки",
+			expr:    "((1+2)*(3+4))/7",
+			want:    3,
+			wantErr: false,
+		},
+		{
+			name:    "унарный минус перед скобками",
+			expr:    "-(2+3)*2",
+			want:    -10,
+			wantErr: false,
+		},
+		{
+			name:    "функции",
+			expr:    "max(1, sqrt(16), 2^2+1)",
+			want:    5,
+			wantErr: false,
+		},
+		{
+			name:    "несвязанная переменная",
+			expr:    "x+1",
+			want:    0,
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -182,3 +333,163 @@ func TestCalc(t *testing.T) {
 		})
 	}
 }
+
+func TestParseExpression_Unary(t *testing.T) {
+	node, err := ParseExpression("-5")
+	if err != nil {
+		t.Fatalf("ParseExpression() error = %v", err)
+	}
+	if node.Token.Type != Number || node.Token.Value != "-5" {
+		t.Errorf("ParseExpression() = %+v, want folded literal -5", node.Token)
+	}
+
+	node, err = ParseExpression("-(1+2)")
+	if err != nil {
+		t.Fatalf("ParseExpression() error = %v", err)
+	}
+	if node.Token.Type != UnaryOperator || node.Left == nil || node.Left.Token.Value != "+" {
+		t.Errorf("ParseExpression() = %+v, want unary minus over 1+2", node.Token)
+	}
+
+	node, err = ParseExpression("+(2*3)")
+	if err != nil {
+		t.Fatalf("ParseExpression() error = %v", err)
+	}
+	if node.Token.Type != Operator || node.Token.Value != "*" {
+		t.Errorf("ParseExpression() = %+v, want unary plus to be dropped", node.Token)
+	}
+}
+
+func TestParseExpression_Power(t *testing.T) {
+	node, err := ParseExpression("2^3^2")
+	if err != nil {
+		t.Fatalf("ParseExpression() error = %v", err)
+	}
+	if node.Token.Value != OpPower || node.Left.Token.Value != "2" || node.Right.Token.Value != OpPower {
+		t.Errorf("2^3^2 must be parsed as 2^(3^2)")
+	}
+
+	node, err = ParseExpression("2*3^2")
+	if err != nil {
+		t.Fatalf("ParseExpression() error = %v", err)
+	}
+	if node.Token.Value != "*" || node.Right.Token.Value != OpPower {
+		t.Errorf("2*3^2 must be parsed as 2*(3^2)")
+	}
+
+	node, err = ParseExpression("-2^2")
+	if err != nil {
+		t.Fatalf("ParseExpression() error = %v", err)
+	}
+	if node.Token.Type != UnaryOperator || node.Left.Token.Value != OpPower {
+		t.Errorf("-2^2 must be parsed as -(2^2)")
+	}
+}
+
+func TestParseExpression_Function(t *testing.T) {
+	node, err := ParseExpression("max(1, 2+3, -4)")
+	if err != nil {
+		t.Fatalf("ParseExpression() error = %v", err)
+	}
+	if node.Token.Type != Function || node.Token.Value != "max" {
+		t.Fatalf("ParseExpression() = %+v, want call of max", node.Token)
+	}
+	if len(node.Args) != 3 {
+		t.Fatalf("max() got %d args, want 3", len(node.Args))
+	}
+	if node.Args[1].Token.Value != "+" || node.Args[2].Token.Value != "-4" {
+		t.Errorf("Unexpected args: %+v, %+v", node.Args[1].Token, node.Args[2].Token)
+	}
+}
+
+func TestApplyFunction(t *testing.T) {
+	tests := []struct {
+		name    string
+		fn      string
+		args    []float64
+		want    float64
+		wantErr bool
+	}{
+		{name: "sqrt", fn: "sqrt", args: []float64{16}, want: 4},
+		{name: "sqrt отрицательного", fn: "sqrt", args: []float64{-1}, wantErr: true},
+		{name: "abs", fn: "abs", args: []float64{-2.5}, want: 2.5},
+		{name: "min", fn: "min", args: []float64{3, 1, 2}, want: 1},
+		{name: "max", fn: "max", args: []float64{3, 1, 2}, want: 3},
+		{name: "log нуля", fn: "log", args: []float64{0}, wantErr: true},
+		{name: "неизвестная функция", fn: "foo", args: []float64{1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyFunction(tt.fn, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ApplyFunction() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ApplyFunction() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindVariables(t *testing.T) {
+	node, err := ParseExpression("-x * (y + 1)")
+	if err != nil {
+		t.Fatalf("ParseExpression() error = %v", err)
+	}
+
+	node, err = BindVariables(node, map[string]float64{"x": 3, "y": 0.5})
+	if err != nil {
+		t.Fatalf("BindVariables() error = %v", err)
+	}
+	if node.Left.Token.Type != Number || node.Left.Token.Value != "-3" {
+		t.Errorf("BindVariables() left = %+v, want folded literal -3", node.Left.Token)
+	}
+	if node.Right.Left.Token.Value != "0.5" {
+		t.Errorf("BindVariables() y = %+v, want 0.5", node.Right.Left.Token)
+	}
+
+	node, _ = ParseExpression("x + z")
+	if _, err := BindVariables(node, map[string]float64{"x": 1}); !errors.Is(err, ErrUnboundVariable) {
+		t.Errorf("BindVariables() error = %v, want %v", err, ErrUnboundVariable)
+	}
+}
+
+func TestParseExpression_ErrorPositions(t *testing.T) {
+	tests := []struct {
+		name      string
+		expr      string
+		wantCode  ParseErrorCode
+		wantPos   int
+		wantToken string
+	}{
+		{name: "некорректный символ", expr: "2 + 2 @ 3", wantCode: CodeUnexpectedChar, wantPos: 6, wantToken: "@"},
+		{name: "многобайтовый символ", expr: "2×3", wantCode: CodeUnexpectedChar, wantPos: 1, wantToken: "×"},
+		{name: "пропущен операнд", expr: "2 + * 3", wantCode: CodeMissingOperand, wantPos: 4, wantToken: "*"},
+		{name: "обрыв выражения", expr: "2 + ", wantCode: CodeUnexpectedEnd, wantPos: 4},
+		{name: "пустое выражение", expr: "   ", wantCode: CodeEmptyExpression, wantPos: 3},
+		{name: "незакрытая скобка", expr: "1 + (2 * 3", wantCode: CodeUnbalancedParens, wantPos: 4, wantToken: "("},
+		{name: "лишняя скобка", expr: "(1 + 2))", wantCode: CodeUnbalancedParens, wantPos: 7, wantToken: ")"},
+		{name: "два числа подряд", expr: "12 34", wantCode: CodeUnexpectedToken, wantPos: 3, wantToken: "34"},
+		{name: "некорректное число", expr: "1 + 1.2.3", wantCode: CodeInvalidNumber, wantPos: 4, wantToken: "1.2.3"},
+		{name: "неизвестная функция", expr: "1 + foo(2)", wantCode: CodeUnknownFunction, wantPos: 4, wantToken: "foo"},
+		{name: "неверное число аргументов", expr: "sqrt(1, 2)", wantCode: CodeWrongArgumentCount, wantPos: 0, wantToken: "sqrt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseExpression(tt.expr)
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("ParseExpression() error = %v, want *ParseError", err)
+			}
+			if parseErr.Code != tt.wantCode || parseErr.Pos != tt.wantPos || parseErr.Token != tt.wantToken {
+				t.Errorf("ParseExpression() error = %+v, want code %s at %d token %q", parseErr, tt.wantCode, tt.wantPos, tt.wantToken)
+			}
+			if !errors.Is(err, ErrInvalidExpression) {
+				t.Errorf("ParseExpression() error must wrap ErrInvalidExpression")
+			}
+		})
+	}
+}
@@ -0,0 +1,374 @@
+package calculator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+	"github.com/superlogarifm/goCalc-v3/internal/storage"
+)
+
+// сообщение для выражений, которые не удалось продолжить после перезапуска сервиса
+const interruptedMessage = "interrupted by service restart"
+
+// NewTaskManagerWithStorage создаёт менеджер, который сохраняет выражения
+// и задачи в репозитории, загружает из них историю и продолжает вычисление
+// незавершённых выражений
+func NewTaskManagerWithStorage(expressions storage.ExpressionRepository, tasks storage.TaskRepository) (*TaskManager, error) {
+	tm := NewTaskManager()
+	tm.expressionRepo = expressions
+	tm.taskRepo = tasks
+	tm.writes = newWriteQueue(expressions, tasks)
+
+	if err := tm.loadHistory(context.Background()); err != nil {
+		return nil, fmt.Errorf("failed to load expressions: %w", err)
+	}
+	return tm, nil
+}
+
+func (tm *TaskManager) loadHistory(ctx context.Context) error {
+	expressions, err := tm.expressionRepo.ListExpressions(ctx)
+	if err != nil {
+		return err
+	}
+	tasks, err := tm.taskRepo.ListTasks(ctx)
+	if err != nil {
+		return err
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	// ID задач и выражений берутся из одного счётчика
+	tasksByExpression := make(map[string][]models.Task)
+	for _, task := range tasks {
+		tm.seedID(task.ID)
+		tasksByExpression[task.ExpressionID] = append(tasksByExpression[task.ExpressionID], task)
+	}
+	for _, expr := range expressions {
+		tm.seedID(expr.ID)
+	}
+
+	recovered := 0
+	for _, expr := range expressions {
+		if isFinished(expr.Status) {
+			tm.expressions[expr.ID] = expr
+			continue
+		}
+		if err := tm.recoverExpression(expr, tasksByExpression[expr.ID]); err != nil {
+			log.Printf("Failed to recover expression %s: %v", expr.ID, err)
+			expr.Status = models.StatusError
+			expr.ErrorMsg = interruptedMessage
+			tm.storeExpression(expr)
+			continue
+		}
+		recovered++
+	}
+
+	log.Printf("Loaded %d expressions from storage, resumed %d", len(expressions), recovered)
+	return nil
+}
+
+// recoverExpression заново строит дерево незавершённого выражения и сопоставляет
+// его узлам сохранённые задачи: они создавались при обходе снизу вверх, поэтому
+// идут в том же порядке по возрастанию ID. Задача подходит узлу, только если
+// совпадают операция и аргументы; после первого несовпадения (запись задачи
+// не сохранилась) сохранённые задачи не используются. Вычисленные задачи не
+// повторяются, недостающие создаются заново. Вызывается под tm.mu.
+func (tm *TaskManager) recoverExpression(expr models.Expression, stored []models.Task) error {
+	ast, err := ParseExpressionFor(expr.Input, expr.Mode)
+	if err != nil {
+		return err
+	}
+	if ast, err = BindVariables(ast, expr.Variables); err != nil {
+		return err
+	}
+	sort.Slice(stored, func(i, j int) bool { return idLess(stored[i].ID, stored[j].ID) })
+
+	tm.expressionASTs[expr.ID] = ast
+	tm.expressions[expr.ID] = expr
+	if ast.Token.Type == Number {
+		result, value, err := tm.evaluateAST(ast, expr)
+		if err != nil {
+			return err
+		}
+		expr.Status = models.StatusCompleted
+		setExpressionResult(&expr, result, value)
+		tm.storeExpression(expr)
+		return nil
+	}
+
+	var failed *models.Task
+	next := 0
+	walkPostOrder(ast, func(node *Node) {
+		if next < len(stored) && tm.matchesNode(stored[next], node) {
+			task := stored[next]
+			next++
+			node.TaskID = task.ID
+			if task.Result != nil || task.Error != nil {
+				tm.tasks[task.ID] = task
+				if task.Error != nil && failed == nil {
+					failed = &task
+				}
+				return
+			}
+			// аргументы собираются заново: подстановка могла не успеть сохраниться
+			task.Arg1, task.Arg2, task.Args = "", "", nil
+			tm.setTaskArgs(&task, node)
+			tm.enqueue(task)
+			return
+		}
+		// дальше сохранённые задачи не соответствуют дереву
+		next = len(stored)
+		tm.enqueue(tm.newTask(node, expr))
+	})
+
+	if failed != nil {
+		tm.failExpression(expr.ID, *failed.Error)
+		return nil
+	}
+	if root, ok := tm.tasks[ast.TaskID]; ok && root.Result != nil {
+		tm.completeExpression(root)
+	}
+	return nil
+}
+
+// matchesNode сообщает, что сохранённая задача вычисляет узел: совпадают операция
+// и аргументы — литералы, ссылки на задачи операндов или их результаты.
+// Вызывается под tm.mu, когда операндам узла уже сопоставлены задачи.
+func (tm *TaskManager) matchesNode(task models.Task, node *Node) bool {
+	if task.Operation != nodeOperation(node) {
+		return false
+	}
+	var args []string
+	switch node.Token.Type {
+	case UnaryOperator:
+		args = []string{task.Arg1}
+	case Operator:
+		args = []string{task.Arg1, task.Arg2}
+	default:
+		args = task.Args
+	}
+	operands := nodeOperands(node)
+	if len(args) != len(operands) {
+		return false
+	}
+	for i, operand := range operands {
+		if args[i] == taskArg(operand) {
+			continue
+		}
+		operandTask, ok := tm.tasks[operand.TaskID]
+		if operand.Token.Type == Number || !ok || operandTask.Result == nil || args[i] != resultValue(operandTask) {
+			return false
+		}
+	}
+	return true
+}
+
+// idLess сравнивает числовые ID
+func idLess(a, b string) bool {
+	x, _ := strconv.ParseInt(a, 10, 64)
+	y, _ := strconv.ParseInt(b, 10, 64)
+	return x < y
+}
+
+// seedID сдвигает счётчик ID за уже использованный. Вызывается под tm.mu.
+func (tm *TaskManager) seedID(id string) {
+	if n, err := strconv.ParseInt(id, 10, 64); err == nil && n > tm.nextID {
+		tm.nextID = n
+	}
+}
+
+// storeTask сохраняет задачу в памяти и ставит её в очередь записи в
+// репозиторий. Вызывается под tm.mu.
+func (tm *TaskManager) storeTask(task models.Task) {
+	tm.tasks[task.ID] = task
+	if tm.writes != nil {
+		tm.writes.addTask(task)
+	}
+}
+
+// storeExpression сохраняет выражение в памяти и ставит его в очередь записи
+// в репозиторий. Вызывается под tm.mu.
+func (tm *TaskManager) storeExpression(expr models.Expression) {
+	tm.expressions[expr.ID] = expr
+	if tm.writes != nil {
+		tm.writes.addExpression(expr)
+	}
+}
+
+// Flush ждёт, пока накопленные изменения будут записаны в репозиторий.
+// Если последняя попытка записи не удалась, возвращает её ошибку:
+// несохранённые изменения остаются в очереди и записываются повторно.
+func (tm *TaskManager) Flush(ctx context.Context) error {
+	if tm.writes == nil {
+		return nil
+	}
+	return tm.writes.flush(ctx)
+}
+
+// persistRetryDelay — пауза перед повторной записью после сбоя репозитория
+const persistRetryDelay = time.Second
+
+// writeQueue записывает изменения в репозитории в фоне, чтобы запросы к
+// TaskManager не ждали базу под tm.mu. От каждого выражения и задачи
+// в очереди остаётся только последнее состояние; выражения пишутся раньше
+// своих задач.
+//
+// Запись отложенная: изменение уже видно клиентам и агентам, но попадёт в базу
+// только при следующей записи очереди — обычно сразу, а при сбое базы через
+// persistRetryDelay и позже. Если процесс аварийно завершится в этом окне,
+// изменение теряется; при восстановлении выражение досчитывается заново
+// с последнего сохранённого состояния (см. recoverExpression). Штатная
+// остановка дожидается записи через Flush.
+type writeQueue struct {
+	expressionRepo storage.ExpressionRepository
+	taskRepo       storage.TaskRepository
+
+	mu          sync.Mutex
+	expressions map[string]models.Expression
+	tasks       map[string]models.Task
+	writing     bool
+	retryDelay  time.Duration
+	err         error         // ошибка последней попытки записи
+	written     chan struct{} // закрывается после каждой попытки записи
+	wake        chan struct{}
+}
+
+func newWriteQueue(expressions storage.ExpressionRepository, tasks storage.TaskRepository) *writeQueue {
+	q := &writeQueue{
+		expressionRepo: expressions,
+		taskRepo:       tasks,
+		expressions:    make(map[string]models.Expression),
+		tasks:          make(map[string]models.Task),
+		retryDelay:     persistRetryDelay,
+		written:        make(chan struct{}),
+		wake:           make(chan struct{}, 1),
+	}
+	go q.run()
+	return q
+}
+
+func (q *writeQueue) addExpression(expr models.Expression) {
+	q.mu.Lock()
+	q.expressions[expr.ID] = expr
+	q.mu.Unlock()
+	q.notify()
+}
+
+func (q *writeQueue) addTask(task models.Task) {
+	// аргументы подставляются в задачу на месте, поэтому очередь держит копию
+	task.Args = append([]string(nil), task.Args...)
+	q.mu.Lock()
+	q.tasks[task.ID] = task
+	q.mu.Unlock()
+	q.notify()
+}
+
+func (q *writeQueue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *writeQueue) run() {
+	for range q.wake {
+		for q.writePending() {
+			time.Sleep(q.retryDelay)
+		}
+	}
+}
+
+// writePending записывает накопленные изменения и возвращает их неудачную
+// часть в очередь, если её не обогнало более новое состояние. Сообщает,
+// что запись нужно повторить.
+func (q *writeQueue) writePending() (retry bool) {
+	q.mu.Lock()
+	expressions, tasks := q.expressions, q.tasks
+	q.expressions = make(map[string]models.Expression)
+	q.tasks = make(map[string]models.Task)
+	q.writing = true
+	q.mu.Unlock()
+
+	ctx := context.Background()
+	failedExpressions := make(map[string]models.Expression)
+	failedTasks := make(map[string]models.Task)
+	var lastErr error
+	exprIDs := make([]string, 0, len(expressions))
+	for id := range expressions {
+		exprIDs = append(exprIDs, id)
+	}
+	taskIDs := make([]string, 0, len(tasks))
+	for id := range tasks {
+		taskIDs = append(taskIDs, id)
+	}
+	// задачи пишутся в порядке создания, выражения — раньше своих задач
+	sort.Slice(exprIDs, func(i, j int) bool { return idLess(exprIDs[i], exprIDs[j]) })
+	sort.Slice(taskIDs, func(i, j int) bool { return idLess(taskIDs[i], taskIDs[j]) })
+
+	for _, id := range exprIDs {
+		expr := expressions[id]
+		if err := q.expressionRepo.SaveExpression(ctx, &expr); err != nil {
+			log.Printf("Failed to persist expression %s: %v", id, err)
+			failedExpressions[id] = expressions[id]
+			lastErr = err
+		}
+	}
+	for _, id := range taskIDs {
+		task := tasks[id]
+		if err := q.taskRepo.SaveTask(ctx, &task); err != nil {
+			log.Printf("Failed to persist task %s (ExprID: %s): %v", id, task.ExpressionID, err)
+			failedTasks[id] = tasks[id]
+			lastErr = err
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for id, expr := range failedExpressions {
+		if _, newer := q.expressions[id]; !newer {
+			q.expressions[id] = expr
+		}
+	}
+	for id, task := range failedTasks {
+		if _, newer := q.tasks[id]; !newer {
+			q.tasks[id] = task
+		}
+	}
+	q.writing = false
+	q.err = lastErr
+	close(q.written)
+	q.written = make(chan struct{})
+	if lastErr != nil {
+		log.Printf("%d expressions and %d tasks are not persisted, retrying in %v",
+			len(q.expressions), len(q.tasks), q.retryDelay)
+		return true
+	}
+	return false
+}
+
+func (q *writeQueue) flush(ctx context.Context) error {
+	for {
+		q.mu.Lock()
+		pending := q.writing || len(q.expressions)+len(q.tasks) > 0
+		err, written := q.err, q.written
+		q.mu.Unlock()
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrStorage, err)
+		}
+		if !pending {
+			return nil
+		}
+		select {
+		case <-written:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package calculator
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+	"github.com/superlogarifm/goCalc-v3/internal/storage"
+)
+
+// fakeRepository хранит выражения и задачи в памяти теста. Записи идут
+// из очереди TaskManager, поэтому тест читает карты после flush.
+type fakeRepository struct {
+	mu          sync.Mutex
+	expressions map[string]models.Expression
+	tasks       map[string]models.Task
+	err         error // ошибка, которую возвращает запись
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		expressions: make(map[string]models.Expression),
+		tasks:       make(map[string]models.Task),
+	}
+}
+
+func (r *fakeRepository) SaveExpression(ctx context.Context, expr *models.Expression) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.expressions[expr.ID] = *expr
+	return nil
+}
+
+func (r *fakeRepository) GetExpression(ctx context.Context, id string) (*models.Expression, error) {
+	expr, ok := r.expressions[id]
+	if !ok {
+		return nil, storage.ErrExpressionNotFound
+	}
+	return &expr, nil
+}
+
+func (r *fakeRepository) ListExpressions(ctx context.Context) ([]models.Expression, error) {
+	var expressions []models.Expression
+	for _, expr := range r.expressions {
+		expressions = append(expressions, expr)
+	}
+	sort.Slice(expressions, func(i, j int) bool {
+		a, _ := strconv.Atoi(expressions[i].ID)
+		b, _ := strconv.Atoi(expressions[j].ID)
+		return a < b
+	})
+	return expressions, nil
+}
+
+func (r *fakeRepository) SaveTask(ctx context.Context, task *models.Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.tasks[task.ID] = *task
+	return nil
+}
+
+func (r *fakeRepository) setErr(err error) {
+	r.mu.Lock()
+	r.err = err
+	r.mu.Unlock()
+}
+
+// flush дожидается записи изменений менеджера в репозиторий
+func flush(t *testing.T, tm *TaskManager) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := tm.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+}
+
+func (r *fakeRepository) ListTasks(ctx context.Context) ([]models.Task, error) {
+	var tasks []models.Task
+	for _, task := range r.tasks {
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+func TestTaskManager_WriteThrough(t *testing.T) {
+	repo := newFakeRepository()
+	tm, err := NewTaskManagerWithStorage(repo, repo)
+	if err != nil {
+		t.Fatalf("NewTaskManagerWithStorage() error = %v", err)
+	}
+
+	id, err := tm.CreateExpression(models.CalculateRequest{Expression: "(1+2)*3", OwnerID: 5})
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+	flush(t, tm)
+	if stored := repo.expressions[id]; stored.Status != models.StatusProcessing || stored.OwnerID != 5 {
+		t.Errorf("stored expression = %+v, want processing owned by 5", stored)
+	}
+	if len(repo.tasks) != 2 {
+		t.Errorf("stored %d tasks, want 2", len(repo.tasks))
+	}
+
+	runTasks(t, tm)
+	flush(t, tm)
+
+	stored := repo.expressions[id]
+	if stored.Status != models.StatusCompleted || stored.Result == nil || *stored.Result != 9 {
+		t.Errorf("stored expression = %+v, want completed with 9", stored)
+	}
+	for _, task := range repo.tasks {
+		if task.Result == nil || HasUnresolvedArgs(task) {
+			t.Errorf("stored task = %+v, want computed with resolved arguments", task)
+		}
+	}
+}
+
+func TestTaskManager_LoadHistory(t *testing.T) {
+	repo := newFakeRepository()
+	tm, _ := NewTaskManagerWithStorage(repo, repo)
+
+	done, _ := tm.CreateExpression(models.CalculateRequest{Expression: "2*2", OwnerID: 1})
+	runTasks(t, tm)
+	pending, _ := tm.CreateExpression(models.CalculateRequest{Expression: "3+3", OwnerID: 1})
+	flush(t, tm)
+
+	restarted, err := NewTaskManagerWithStorage(repo, repo)
+	if err != nil {
+		t.Fatalf("NewTaskManagerWithStorage() error = %v", err)
+	}
+
+	expr, ok := restarted.GetUserExpression(done, 1)
+	if !ok || expr.Status != models.StatusCompleted || *expr.Result != 4 {
+		t.Errorf("restored expression = %+v, want completed with 4", expr)
+	}
+	expr, ok = restarted.GetUserExpression(pending, 1)
+	if !ok || expr.Status != models.StatusProcessing {
+		t.Errorf("restored expression = %+v, want processing", expr)
+	}
+
+	id, err := restarted.CreateExpression(models.CalculateRequest{Expression: "1+1", OwnerID: 1})
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+	flush(t, restarted)
+	newID, _ := strconv.Atoi(id)
+	for _, task := range repo.tasks {
+		if n, _ := strconv.Atoi(task.ID); n >= newID && task.ExpressionID != id {
+			t.Errorf("new expression ID %s does not follow stored task %s", id, task.ID)
+		}
+	}
+}
+
+func TestTaskManager_RecoverPartialExpression(t *testing.T) {
+	repo := newFakeRepository()
+	tm, _ := NewTaskManagerWithStorage(repo, repo)
+
+	id, err := tm.CreateExpression(models.CalculateRequest{Expression: "(1+2)*(x+4)", Variables: map[string]float64{"x": 3}})
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+	task, _ := tm.GetNextTask()
+	if err := tm.UpdateTaskResult(EvaluateTask(*task)); err != nil {
+		t.Fatalf("UpdateTaskResult() error = %v", err)
+	}
+	// второй задаче выдана аренда, но результат до перезапуска не вернулся
+	tm.GetNextTask()
+	flush(t, tm)
+
+	restarted, err := NewTaskManagerWithStorage(repo, repo)
+	if err != nil {
+		t.Fatalf("NewTaskManagerWithStorage() error = %v", err)
+	}
+	if n := runTasks(t, restarted); n != 2 {
+		t.Errorf("evaluated %d tasks after restart, want 2", n)
+	}
+
+	expr, _ := restarted.GetExpression(id)
+	if expr.Status != models.StatusCompleted || expr.Result == nil || *expr.Result != 21 {
+		t.Errorf("Expression = %+v, want completed with 21", expr)
+	}
+}
+
+func TestTaskManager_RecoverUnsavedSubstitution(t *testing.T) {
+	repo := newFakeRepository()
+	tm, _ := NewTaskManagerWithStorage(repo, repo)
+
+	id, _ := tm.CreateExpression(models.CalculateRequest{Expression: "2*3+1"})
+	task, _ := tm.GetNextTask()
+	tm.UpdateTaskResult(EvaluateTask(*task))
+	flush(t, tm)
+
+	// сбой между сохранением результата и подстановкой его в зависимую задачу
+	for taskID, stored := range repo.tasks {
+		if stored.Operation == "+" {
+			stored.Arg1 = taskRefPrefix + task.ID
+			repo.tasks[taskID] = stored
+		}
+	}
+
+	restarted, _ := NewTaskManagerWithStorage(repo, repo)
+	next, ok := restarted.GetNextTask()
+	if !ok || next.Operation != "+" || next.Arg1 != "6" {
+		t.Fatalf("GetNextTask() = %+v, want 6 + 1", next)
+	}
+	restarted.UpdateTaskResult(EvaluateTask(*next))
+
+	expr, _ := restarted.GetExpression(id)
+	if expr.Status != models.StatusCompleted || *expr.Result != 7 {
+		t.Errorf("Expression = %+v, want completed with 7", expr)
+	}
+}
+
+func TestTaskManager_RecoverMissingTasks(t *testing.T) {
+	repo := newFakeRepository()
+	tm, _ := NewTaskManagerWithStorage(repo, repo)
+
+	id, _ := tm.CreateExpression(models.CalculateRequest{Expression: "(1+2)*3"})
+	flush(t, tm)
+	// сбой до сохранения последней задачи
+	for taskID, stored := range repo.tasks {
+		if stored.Operation == "*" {
+			delete(repo.tasks, taskID)
+		}
+	}
+
+	restarted, _ := NewTaskManagerWithStorage(repo, repo)
+	if n := runTasks(t, restarted); n != 2 {
+		t.Errorf("evaluated %d tasks after restart, want 2", n)
+	}
+	expr, _ := restarted.GetExpression(id)
+	if expr.Status != models.StatusCompleted || *expr.Result != 9 {
+		t.Errorf("Expression = %+v, want completed with 9", expr)
+	}
+}
+
+func TestTaskManager_RecoverMissingMiddleTask(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       float64
+	}{
+		{name: "разные операнды", expression: "(1+2)+(3+4)", want: 10},
+		{name: "одинаковые операнды", expression: "(1+2)*(1+2)", want: 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepository()
+			tm, _ := NewTaskManagerWithStorage(repo, repo)
+
+			id, _ := tm.CreateExpression(models.CalculateRequest{Expression: tt.expression})
+			tm.mu.Lock()
+			root := tm.expressionASTs[id].TaskID
+			tm.mu.Unlock()
+			// операнды вычислены, корень выдан, но результат не вернулся
+			for {
+				task, ok := tm.GetNextTask()
+				if !ok || task.ID == root {
+					break
+				}
+				tm.UpdateTaskResult(EvaluateTask(*task))
+			}
+			flush(t, tm)
+			// не сохранилась первая задача: результат второй не должен
+			// встать на её место
+			first := ""
+			for taskID := range repo.tasks {
+				if first == "" || idLess(taskID, first) {
+					first = taskID
+				}
+			}
+			delete(repo.tasks, first)
+
+			restarted, _ := NewTaskManagerWithStorage(repo, repo)
+			runTasks(t, restarted)
+			expr, _ := restarted.GetExpression(id)
+			if expr.Status != models.StatusCompleted || *expr.Result != tt.want {
+				t.Errorf("Expression = %+v, want completed with %v", expr, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskManager_RecoverInvalidExpression(t *testing.T) {
+	repo := newFakeRepository()
+	repo.expressions["1"] = models.Expression{ID: "1", Input: "2 +", Status: models.StatusProcessing}
+
+	tm, err := NewTaskManagerWithStorage(repo, repo)
+	if err != nil {
+		t.Fatalf("NewTaskManagerWithStorage() error = %v", err)
+	}
+	expr, _ := tm.GetExpression("1")
+	if expr.Status != models.StatusError || expr.ErrorMsg != interruptedMessage {
+		t.Errorf("Expression = %+v, want interrupted", expr)
+	}
+}
+
+func TestTaskManager_PersistRetry(t *testing.T) {
+	repo := newFakeRepository()
+	tm, _ := NewTaskManagerWithStorage(repo, repo)
+	tm.writes.retryDelay = 10 * time.Millisecond
+
+	id, err := tm.CreateExpression(models.CalculateRequest{Expression: "2*3"})
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+	flush(t, tm)
+
+	// база недоступна: результат принимается в памяти, сбой виден через Flush
+	repo.setErr(errors.New("connection refused"))
+	runTasks(t, tm)
+	if err := tm.Flush(context.Background()); !errors.Is(err, ErrStorage) {
+		t.Fatalf("Flush() error = %v, want %v", err, ErrStorage)
+	}
+	if expr, _ := tm.GetExpression(id); expr.Status != models.StatusCompleted {
+		t.Errorf("Expression = %+v, want completed in memory", expr)
+	}
+
+	// после восстановления базы несохранённые изменения записываются повторно
+	repo.setErr(nil)
+	deadline := time.Now().Add(2 * time.Second)
+	for tm.Flush(context.Background()) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("changes were not persisted after the repository recovered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if stored := repo.expressions[id]; stored.Status != models.StatusCompleted || stored.Result == nil || *stored.Result != 6 {
+		t.Errorf("stored expression = %+v, want completed with 6", stored)
+	}
+}
+
+func TestTaskManager_CreateExpressionStorageError(t *testing.T) {
+	repo := newFakeRepository()
+	tm, _ := NewTaskManagerWithStorage(repo, repo)
+	repo.setErr(errors.New("connection refused"))
+
+	if _, err := tm.CreateExpression(models.CalculateRequest{Expression: "2*3"}); !errors.Is(err, ErrStorage) {
+		t.Fatalf("CreateExpression() error = %v, want %v", err, ErrStorage)
+	}
+	if expressions := tm.GetAllExpressions(); len(expressions) != 0 {
+		t.Errorf("expressions = %+v, want none after failed save", expressions)
+	}
+}
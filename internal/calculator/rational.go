@@ -0,0 +1,64 @@
+package calculator
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+)
+
+// RationalArithmetic — точные вычисления в обыкновенных дробях.
+// Операции, результат которых может быть иррациональным, завершаются ошибкой.
+type RationalArithmetic struct {
+	ratArithmetic
+}
+
+func (RationalArithmetic) Mode() models.CalculationMode { return models.ModeRational }
+
+// Format возвращает дробь в виде "a/b" или целое число
+func (RationalArithmetic) Format(n Value) string {
+	return rat(n).RatString()
+}
+
+func (RationalArithmetic) FromFloat(f float64) (Value, error) {
+	return nil, fmt.Errorf("%w in rational mode", ErrInexactResult)
+}
+
+func (RationalArithmetic) Pow(a, b Value) (Value, error) {
+	if !isExactExponent(rat(b)) {
+		return nil, fmt.Errorf("%w in rational mode: non-integer exponent %s", ErrInexactResult, rat(b).RatString())
+	}
+	result, err := exactPower(rat(a), rat(b))
+	if err != nil {
+		return nil, err
+	}
+	return ratNumber{result}, nil
+}
+
+// Sqrt извлекает корень, только если числитель и знаменатель — точные квадраты
+func (RationalArithmetic) Sqrt(a Value) (Value, error) {
+	r := rat(a)
+	if r.Sign() < 0 {
+		return nil, fmt.Errorf("square root of negative number: %s", r.RatString())
+	}
+	num, okNum := exactSqrt(r.Num())
+	denom, okDenom := exactSqrt(r.Denom())
+	if !okNum || !okDenom {
+		return nil, fmt.Errorf("%w in rational mode: sqrt(%s)", ErrInexactResult, r.RatString())
+	}
+	return ratNumber{new(big.Rat).SetFrac(num, denom)}, nil
+}
+
+func exactSqrt(x *big.Int) (*big.Int, bool) {
+	root := new(big.Int).Sqrt(x)
+	return root, new(big.Int).Mul(root, root).Cmp(x) == 0
+}
+
+// NewFraction раскладывает значение рационального режима на числитель и знаменатель
+func NewFraction(value string) *models.Fraction {
+	r, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return nil
+	}
+	return &models.Fraction{Numerator: r.Num().String(), Denominator: r.Denom().String()}
+}
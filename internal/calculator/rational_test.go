@@ -0,0 +1,81 @@
+package calculator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+)
+
+func TestRationalArithmetic(t *testing.T) {
+	tests := []struct {
+		name    string
+		op      string
+		args    []string
+		want    string
+		wantErr error
+	}{
+		{name: "сложение дробей", op: "+", args: []string{"1/3", "1/6"}, want: "1/2"},
+		{name: "десятичный литерал", op: "*", args: []string{"0.5", "3"}, want: "3/2"},
+		{name: "деление", op: "/", args: []string{"1", "3"}, want: "1/3"},
+		{name: "целый результат", op: "*", args: []string{"2/3", "3/2"}, want: "1"},
+		{name: "отрицательная степень", op: "^", args: []string{"2/3", "-2"}, want: "9/4"},
+		{name: "точный корень", op: "sqrt", args: []string{"4/9"}, want: "2/3"},
+		{name: "иррациональный корень", op: "sqrt", args: []string{"2"}, wantErr: ErrInexactResult},
+		{name: "дробная степень", op: "^", args: []string{"2", "1/2"}, wantErr: ErrInexactResult},
+		{name: "тригонометрия", op: "sin", args: []string{"1"}, wantErr: ErrInexactResult},
+		{name: "min", op: "min", args: []string{"1/2", "1/3"}, want: "1/3"},
+		{name: "abs", op: "abs", args: []string{"-3/4"}, want: "3/4"},
+	}
+
+	ar := RationalArithmetic{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Evaluate(ar, tt.op, tt.args)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Evaluate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Evaluate() error = %v", err)
+			}
+			if ar.Format(got) != tt.want {
+				t.Errorf("Evaluate() = %v, want %v", ar.Format(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskManager_RationalMode(t *testing.T) {
+	tm := NewTaskManager()
+	id, err := tm.CreateExpression(models.CalculateRequest{Expression: "1/3", Mode: models.ModeRational})
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+
+	if _, ok := tm.GetNextTaskFor([]models.CalculationMode{models.ModeFloat}); ok {
+		t.Fatalf("GetNextTaskFor() returned a rational task to a float-only agent")
+	}
+
+	task, ok := tm.GetNextTaskFor([]models.CalculationMode{models.ModeFloat, models.ModeRational})
+	if !ok {
+		t.Fatalf("GetNextTaskFor() returned no task")
+	}
+
+	if err := tm.UpdateTaskResult(EvaluateTask(*task)); err != nil {
+		t.Fatalf("UpdateTaskResult() error = %v", err)
+	}
+
+	expr, ok := tm.GetExpression(id)
+	if !ok {
+		t.Fatalf("GetExpression() returned no expression")
+	}
+	if expr.Status != models.StatusCompleted || expr.Value != "1/3" {
+		t.Fatalf("Expression = %+v, want completed with value 1/3", expr)
+	}
+	if expr.Fraction == nil || expr.Fraction.Numerator != "1" || expr.Fraction.Denominator != "3" {
+		t.Errorf("Expression fraction = %+v, want 1/3", expr.Fraction)
+	}
+}
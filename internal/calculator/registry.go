@@ -0,0 +1,338 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// OperationKind — синтаксическая форма операции в выражении
+type OperationKind int
+
+const (
+	BinaryOperator OperationKind = iota
+	PrefixOperator
+	FunctionCall
+)
+
+// операция унарного плюса; в AST не попадает
+const OpPlus = "pos"
+
+// DefaultOperationTime — время операции в миллисекундах, если оно не задано
+const DefaultOperationTime int64 = 1000
+
+var ErrInvalidOperation = errors.New("invalid operation definition")
+
+// OperationSpec описывает оператор или функцию. Парсер, планировщик задач,
+// агенты и Calc берут из реестра синтаксис, арность, время и вычисление.
+type OperationSpec struct {
+	Name    string   // имя операции в задачах
+	Symbols []string // запись оператора в выражении; у функций совпадает с Name
+	Kind    OperationKind
+	MinArgs int
+	MaxArgs int // -1 — число аргументов не ограничено
+
+	Precedence       int // для операторов; больше — связывает сильнее
+	RightAssociative bool
+
+	TimeEnv     string // переменная окружения со временем операции в мс
+	DefaultTime int64
+
+	Eval Operation
+}
+
+// Operation вычисляет оператор или функцию над разобранными аргументами
+type Operation func(ar Arithmetic, args []Value) (Value, error)
+
+type registry struct {
+	mu     sync.RWMutex
+	byName map[string]*OperationSpec
+	// операторы по записи в выражении, отдельно для бинарной и префиксной формы
+	binary map[string]*OperationSpec
+	prefix map[string]*OperationSpec
+	// symbols — записи операторов обеих форм, от длинных к коротким
+	symbols []string
+}
+
+var operations = &registry{
+	byName: make(map[string]*OperationSpec),
+	binary: make(map[string]*OperationSpec),
+	prefix: make(map[string]*OperationSpec),
+}
+
+func init() {
+	for _, spec := range []OperationSpec{
+		{Name: "+", Kind: BinaryOperator, Precedence: 1, TimeEnv: "TIME_ADDITION_MS", Eval: binary(Arithmetic.Add)},
+		{Name: "-", Kind: BinaryOperator, Precedence: 1, TimeEnv: "TIME_SUBTRACTION_MS", Eval: binary(Arithmetic.Sub)},
+		{Name: "*", Kind: BinaryOperator, Precedence: 2, TimeEnv: "TIME_MULTIPLICATIONS_MS", Eval: binary(Arithmetic.Mul)},
+		{Name: "/", Kind: BinaryOperator, Precedence: 2, TimeEnv: "TIME_DIVISIONS_MS", Eval: binary(Arithmetic.Div)},
+		{Name: OpPower, Symbols: []string{"^", "**"}, Kind: BinaryOperator, Precedence: 4, RightAssociative: true,
+			TimeEnv: "TIME_POWER_MS", Eval: binary(Arithmetic.Pow)},
+		// унарные операторы связывают сильнее умножения, но слабее степени: -2^2 = -4
+		{Name: OpNegate, Symbols: []string{"-"}, Kind: PrefixOperator, Precedence: 3, TimeEnv: "TIME_NEGATION_MS", Eval: unary(Arithmetic.Neg)},
+		{Name: OpPlus, Symbols: []string{"+"}, Kind: PrefixOperator, Precedence: 3, Eval: identityOperation},
+		{Name: "sqrt", Kind: FunctionCall, MinArgs: 1, MaxArgs: 1, TimeEnv: "TIME_FUNCTION_MS", Eval: unary(Arithmetic.Sqrt)},
+		{Name: "abs", Kind: FunctionCall, MinArgs: 1, MaxArgs: 1, TimeEnv: "TIME_FUNCTION_MS", Eval: absOperation},
+		{Name: "min", Kind: FunctionCall, MinArgs: 1, MaxArgs: -1, TimeEnv: "TIME_FUNCTION_MS", Eval: extremumOperation(-1)},
+		{Name: "max", Kind: FunctionCall, MinArgs: 1, MaxArgs: -1, TimeEnv: "TIME_FUNCTION_MS", Eval: extremumOperation(1)},
+		{Name: "sin", Kind: FunctionCall, MinArgs: 1, MaxArgs: 1, TimeEnv: "TIME_FUNCTION_MS", Eval: floatOperation(func(x float64) (float64, error) {
+			return math.Sin(x), nil
+		})},
+		{Name: "cos", Kind: FunctionCall, MinArgs: 1, MaxArgs: 1, TimeEnv: "TIME_FUNCTION_MS", Eval: floatOperation(func(x float64) (float64, error) {
+			return math.Cos(x), nil
+		})},
+		{Name: "log", Kind: FunctionCall, MinArgs: 1, MaxArgs: 1, TimeEnv: "TIME_FUNCTION_MS", Eval: floatOperation(func(x float64) (float64, error) {
+			if x <= 0 {
+				return 0, fmt.Errorf("logarithm of non-positive number: %g", x)
+			}
+			return math.Log(x), nil
+		})},
+	} {
+		if err := Register(spec); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Register добавляет операцию в реестр. Для операторов арность выводится
+// из формы, время по умолчанию — DefaultOperationTime.
+func Register(spec OperationSpec) error {
+	switch spec.Kind {
+	case BinaryOperator:
+		spec.MinArgs, spec.MaxArgs = 2, 2
+	case PrefixOperator:
+		spec.MinArgs, spec.MaxArgs = 1, 1
+	case FunctionCall:
+		spec.Symbols = []string{spec.Name}
+	default:
+		return fmt.Errorf("%w: unknown kind %d of %s", ErrInvalidOperation, spec.Kind, spec.Name)
+	}
+	if len(spec.Symbols) == 0 {
+		spec.Symbols = []string{spec.Name}
+	}
+	if spec.DefaultTime <= 0 {
+		spec.DefaultTime = DefaultOperationTime
+	}
+	if err := validateSpec(&spec); err != nil {
+		return err
+	}
+
+	operations.mu.Lock()
+	defer operations.mu.Unlock()
+
+	if _, exists := operations.byName[spec.Name]; exists {
+		return fmt.Errorf("%w: %s is already registered", ErrInvalidOperation, spec.Name)
+	}
+	symbols := operations.symbolsFor(spec.Kind)
+	for _, symbol := range spec.Symbols {
+		if _, exists := symbols[symbol]; exists {
+			return fmt.Errorf("%w: symbol %s is already registered", ErrInvalidOperation, symbol)
+		}
+	}
+
+	operations.byName[spec.Name] = &spec
+	for _, symbol := range spec.Symbols {
+		symbols[symbol] = &spec
+	}
+	if spec.Kind != FunctionCall {
+		operations.sortSymbols()
+	}
+	return nil
+}
+
+func validateSpec(spec *OperationSpec) error {
+	if spec.Name == "" {
+		return fmt.Errorf("%w: empty name", ErrInvalidOperation)
+	}
+	if spec.Eval == nil {
+		return fmt.Errorf("%w: %s has no evaluation function", ErrInvalidOperation, spec.Name)
+	}
+	if spec.MinArgs < 0 || (spec.MaxArgs >= 0 && spec.MaxArgs < spec.MinArgs) {
+		return fmt.Errorf("%w: %s has invalid arity %d..%d", ErrInvalidOperation, spec.Name, spec.MinArgs, spec.MaxArgs)
+	}
+
+	if spec.Kind == FunctionCall {
+		for i, ch := range spec.Name {
+			if (i == 0 && !isIdentifierStart(ch)) || !isIdentifierPart(ch) {
+				return fmt.Errorf("%w: function name %q is not an identifier", ErrInvalidOperation, spec.Name)
+			}
+		}
+		return nil
+	}
+
+	if spec.Precedence <= 0 {
+		return fmt.Errorf("%w: operator %s needs a positive precedence", ErrInvalidOperation, spec.Name)
+	}
+	for _, symbol := range spec.Symbols {
+		if symbol == "" || strings.IndexFunc(symbol, isReservedRune) >= 0 {
+			return fmt.Errorf("%w: operator symbol %q", ErrInvalidOperation, symbol)
+		}
+	}
+	return nil
+}
+
+// isReservedRune сообщает, что символ занят числами, именами или скобками
+// и не может входить в запись оператора
+func isReservedRune(ch rune) bool {
+	return isIdentifierPart(ch) || unicode.IsSpace(ch) || strings.ContainsRune(".(),", ch)
+}
+
+func (r *registry) symbolsFor(kind OperationKind) map[string]*OperationSpec {
+	switch kind {
+	case BinaryOperator:
+		return r.binary
+	case PrefixOperator:
+		return r.prefix
+	default:
+		return r.byName
+	}
+}
+
+// LookupOperation возвращает описание операции задачи по её имени
+func LookupOperation(name string) (*OperationSpec, bool) {
+	operations.mu.RLock()
+	defer operations.mu.RUnlock()
+	spec, ok := operations.byName[name]
+	return spec, ok
+}
+
+// OperationNames возвращает имена всех зарегистрированных операций по алфавиту
+func OperationNames() []string {
+	operations.mu.RLock()
+	defer operations.mu.RUnlock()
+
+	names := make([]string, 0, len(operations.byName))
+	for name := range operations.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortSymbols пересобирает список записей операторов. Вызывается под r.mu.
+func (r *registry) sortSymbols() {
+	symbols := make([]string, 0, len(r.binary)+len(r.prefix))
+	for symbol := range r.binary {
+		symbols = append(symbols, symbol)
+	}
+	for symbol := range r.prefix {
+		if _, ok := r.binary[symbol]; !ok {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Slice(symbols, func(i, j int) bool {
+		if len(symbols[i]) != len(symbols[j]) {
+			return len(symbols[i]) > len(symbols[j])
+		}
+		return symbols[i] < symbols[j]
+	})
+	r.symbols = symbols
+}
+
+// lookupOperator находит оператор заданной формы по записи в выражении
+func lookupOperator(kind OperationKind, symbol string) (*OperationSpec, bool) {
+	operations.mu.RLock()
+	defer operations.mu.RUnlock()
+	spec, ok := operations.symbolsFor(kind)[symbol]
+	return spec, ok
+}
+
+// matchOperatorSymbol возвращает самую длинную запись оператора в начале s
+func matchOperatorSymbol(s string) string {
+	operations.mu.RLock()
+	defer operations.mu.RUnlock()
+
+	for _, symbol := range operations.symbols {
+		if strings.HasPrefix(s, symbol) {
+			return symbol
+		}
+	}
+	return ""
+}
+
+// IsFunction сообщает, является ли операция вызовом функции
+func IsFunction(name string) bool {
+	spec, ok := LookupOperation(name)
+	return ok && spec.Kind == FunctionCall
+}
+
+// checkArity проверяет число аргументов операции
+func checkArity(name string, argc int) error {
+	spec, ok := LookupOperation(name)
+	if !ok {
+		return fmt.Errorf("unknown operation: %s", name)
+	}
+	if argc < spec.MinArgs || (spec.MaxArgs >= 0 && argc > spec.MaxArgs) {
+		return fmt.Errorf("wrong number of arguments for %s: %d", name, argc)
+	}
+	return nil
+}
+
+// ApplyFunction вычисляет встроенную функцию в float64 от уже вычисленных аргументов
+func ApplyFunction(name string, args []float64) (float64, error) {
+	if !IsFunction(name) {
+		return 0, fmt.Errorf("unknown function: %s", name)
+	}
+	values := make([]Value, len(args))
+	for i, arg := range args {
+		values[i] = floatNumber(arg)
+	}
+	result, err := applyOperation(FloatArithmetic{}, name, values)
+	if err != nil {
+		return 0, err
+	}
+	return result.Float64(), nil
+}
+
+func binary(f func(Arithmetic, Value, Value) (Value, error)) Operation {
+	return func(ar Arithmetic, args []Value) (Value, error) {
+		return f(ar, args[0], args[1])
+	}
+}
+
+func unary(f func(Arithmetic, Value) (Value, error)) Operation {
+	return func(ar Arithmetic, args []Value) (Value, error) {
+		return f(ar, args[0])
+	}
+}
+
+func identityOperation(ar Arithmetic, args []Value) (Value, error) {
+	return args[0], nil
+}
+
+func absOperation(ar Arithmetic, args []Value) (Value, error) {
+	zero, _ := ar.Parse("0")
+	if ar.Cmp(args[0], zero) < 0 {
+		return ar.Neg(args[0])
+	}
+	return args[0], nil
+}
+
+// extremumOperation выбирает минимум (sign = -1) или максимум (sign = 1)
+func extremumOperation(sign int) Operation {
+	return func(ar Arithmetic, args []Value) (Value, error) {
+		result := args[0]
+		for _, arg := range args[1:] {
+			if ar.Cmp(arg, result) == sign {
+				result = arg
+			}
+		}
+		return result, nil
+	}
+}
+
+// floatOperation считает функцию одного аргумента в float64: тригонометрия
+// и логарифм не имеют точного представления ни в одном режиме
+func floatOperation(f func(float64) (float64, error)) Operation {
+	return func(ar Arithmetic, args []Value) (Value, error) {
+		result, err := f(args[0].Float64())
+		if err != nil {
+			return nil, err
+		}
+		return ar.FromFloat(result)
+	}
+}
@@ -0,0 +1,137 @@
+package calculator
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+)
+
+func modulo(ar Arithmetic, args []Value) (Value, error) {
+	if args[1].Float64() == 0 {
+		return nil, errors.New("division by zero")
+	}
+	return ar.FromFloat(math.Mod(args[0].Float64(), args[1].Float64()))
+}
+
+func TestRegister_NewOperator(t *testing.T) {
+	t.Setenv("TIME_MODULO_MS", "7")
+	if err := Register(OperationSpec{Name: "%", Kind: BinaryOperator, Precedence: 2, TimeEnv: "TIME_MODULO_MS", Eval: modulo}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	got, err := Calc("1 + 17 % 5 * 2")
+	if err != nil {
+		t.Fatalf("Calc() error = %v", err)
+	}
+	if got != 5 {
+		t.Errorf("Calc() = %v, want 5", got)
+	}
+
+	tm := NewTaskManager()
+	if _, err := tm.CreateExpression(models.CalculateRequest{Expression: "17 % 5"}); err != nil {
+		t.Fatalf("CreateExpression() error = %v", err)
+	}
+	task, ok := tm.GetNextTask()
+	if !ok {
+		t.Fatalf("GetNextTask() returned no task")
+	}
+	if task.Operation != "%" || task.OperationTime != 7 {
+		t.Errorf("Task = %+v, want operation %% with time 7", task)
+	}
+	if result := EvaluateTask(*task); result.Error != nil || result.Result != 2 {
+		t.Errorf("EvaluateTask() = %+v, want 2", result)
+	}
+}
+
+func TestRegister_LongestSymbol(t *testing.T) {
+	floorDiv := func(ar Arithmetic, args []Value) (Value, error) {
+		if args[1].Float64() == 0 {
+			return nil, errors.New("division by zero")
+		}
+		return ar.FromFloat(math.Floor(args[0].Float64() / args[1].Float64()))
+	}
+	if err := Register(OperationSpec{Name: "floordiv", Symbols: []string{"//"}, Kind: BinaryOperator, Precedence: 2, Eval: floorDiv}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	// запись, добавленная после инициализации, сравнивается раньше своего префикса "/"
+	for input, want := range map[string]string{"//2": "//", "/2": "/", "**2": "**", "-2": "-", "x": ""} {
+		if got := matchOperatorSymbol(input); got != want {
+			t.Errorf("matchOperatorSymbol(%q) = %q, want %q", input, got, want)
+		}
+	}
+	got, err := Calc("7 // 2 / 2")
+	if err != nil {
+		t.Fatalf("Calc() error = %v", err)
+	}
+	if got != 1.5 {
+		t.Errorf("Calc() = %v, want 1.5", got)
+	}
+}
+
+func TestRegister_NewFunction(t *testing.T) {
+	hypot := func(ar Arithmetic, args []Value) (Value, error) {
+		return ar.FromFloat(math.Hypot(args[0].Float64(), args[1].Float64()))
+	}
+	if err := Register(OperationSpec{Name: "hypot", Kind: FunctionCall, MinArgs: 2, MaxArgs: 2, Eval: hypot}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	got, err := Calc("hypot(3, 4)")
+	if err != nil || got != 5 {
+		t.Errorf("Calc() = %v, %v, want 5", got, err)
+	}
+	if getOperationTime("hypot") != DefaultOperationTime {
+		t.Errorf("getOperationTime() = %d, want default", getOperationTime("hypot"))
+	}
+
+	var parseErr *ParseError
+	if _, err := ParseExpression("hypot(3)"); !errors.As(err, &parseErr) || parseErr.Code != CodeWrongArgumentCount {
+		t.Errorf("ParseExpression() error = %v, want %s", err, CodeWrongArgumentCount)
+	}
+}
+
+func TestRegister_Validation(t *testing.T) {
+	eval := func(ar Arithmetic, args []Value) (Value, error) { return args[0], nil }
+	tests := []struct {
+		name string
+		spec OperationSpec
+	}{
+		{name: "повторное имя", spec: OperationSpec{Name: "max", Kind: FunctionCall, MinArgs: 1, MaxArgs: 1, Eval: eval}},
+		{name: "занятый символ", spec: OperationSpec{Name: "plus", Symbols: []string{"+"}, Kind: BinaryOperator, Precedence: 1, Eval: eval}},
+		{name: "без вычисления", spec: OperationSpec{Name: "noop", Kind: FunctionCall, MinArgs: 1, MaxArgs: 1}},
+		{name: "функция не идентификатор", spec: OperationSpec{Name: "2x", Kind: FunctionCall, MinArgs: 1, MaxArgs: 1, Eval: eval}},
+		{name: "символ из букв", spec: OperationSpec{Name: "mod", Kind: BinaryOperator, Precedence: 2, Eval: eval}},
+		{name: "символ со скобкой", spec: OperationSpec{Name: "<(", Kind: BinaryOperator, Precedence: 2, Eval: eval}},
+		{name: "без приоритета", spec: OperationSpec{Name: "&", Kind: BinaryOperator, Eval: eval}},
+		{name: "неверная арность", spec: OperationSpec{Name: "bad", Kind: FunctionCall, MinArgs: 2, MaxArgs: 1, Eval: eval}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Register(tt.spec); !errors.Is(err, ErrInvalidOperation) {
+				t.Errorf("Register() error = %v, want %v", err, ErrInvalidOperation)
+			}
+		})
+	}
+}
+
+func TestGetOperationTime(t *testing.T) {
+	t.Setenv("TIME_POWER_MS", "250")
+	tests := []struct {
+		operation string
+		want      int64
+	}{
+		{operation: OpPower, want: 250},
+		{operation: "+", want: DefaultOperationTime},
+		{operation: "unknown", want: DefaultOperationTime},
+	}
+
+	for _, tt := range tests {
+		if got := getOperationTime(tt.operation); got != tt.want {
+			t.Errorf("getOperationTime(%q) = %d, want %d", tt.operation, got, tt.want)
+		}
+	}
+}
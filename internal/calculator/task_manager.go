@@ -1,9 +1,12 @@
 package calculator
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -11,21 +14,58 @@ import (
 	"time"
 
 	"github.com/superlogarifm/goCalc-v3/internal/models"
+	"github.com/superlogarifm/goCalc-v3/internal/storage"
 )
 
+var (
+	ErrTaskNotFound       = errors.New("task not found")
+	ErrTaskCompleted      = errors.New("task already completed")
+	ErrExpressionNotFound = errors.New("expression not found")
+	ErrExpressionFinished = errors.New("expression already finished")
+	// ErrStorage — сбой репозитория, а не ошибка в запросе клиента
+	ErrStorage = errors.New("storage error")
+)
+
+// TaskManager разбивает выражения на задачи и выдаёт агентам только те,
+// все аргументы которых уже известны
 type TaskManager struct {
-	tasks          sync.Map
-	expressions    sync.Map
+	mu             sync.Mutex
+	tasks          map[string]models.Task
+	expressions    map[string]models.Expression
 	expressionASTs map[string]*Node
-	taskQueue      chan models.Task
-	nextID         int64
+	// dependents — задачи, ожидающие результата задачи с данным ID
+	dependents map[string][]string
+	// ready — очередь готовых к вычислению задач в порядке поступления
+	ready []string
+	// readySignal закрывается и заменяется новым, когда в очереди появляются
+	// задачи, и будит всех, кто ждёт в WaitNextTasksForAgent
+	readySignal chan struct{}
+	// leases — выданные агентам задачи; attempts — сколько раз задача выдавалась
+	leases     map[string]models.TaskLease
+	attempts   map[string]int
+	leaseGrace time.Duration
+	now        func() time.Time
+	nextID     int64
+
+	// репозитории для сохранения состояния; nil — только в памяти. Изменения
+	// пишутся в них через writes вне tm.mu.
+	expressionRepo storage.ExpressionRepository
+	taskRepo       storage.TaskRepository
+	writes         *writeQueue
 }
 
 func NewTaskManager() *TaskManager {
 	return &TaskManager{
-		taskQueue:      make(chan models.Task, 100),
-		nextID:         1,
+		tasks:          make(map[string]models.Task),
+		expressions:    make(map[string]models.Expression),
 		expressionASTs: make(map[string]*Node),
+		dependents:     make(map[string][]string),
+		readySignal:    make(chan struct{}),
+		leases:         make(map[string]models.TaskLease),
+		attempts:       make(map[string]int),
+		leaseGrace:     leaseGraceFromEnv(),
+		now:            time.Now,
+		nextID:         1,
 	}
 }
 
@@ -33,281 +73,606 @@ func (tm *TaskManager) generateID() string {
 	return fmt.Sprintf("%d", atomic.AddInt64(&tm.nextID, 1))
 }
 
+// getOperationTime возвращает время операции из её переменной окружения в реестре
 func getOperationTime(operation string) int64 {
-	var envVar string
-	switch operation {
-	case "+":
-		envVar = "TIME_ADDITION_MS"
-	case "-":
-		envVar = "TIME_SUBTRACTION_MS"
-	case "*":
-		envVar = "TIME_MULTIPLICATIONS_MS"
-	case "/":
-		envVar = "TIME_DIVISIONS_MS"
-	default:
-		return 1000 // default 1 second
+	spec, ok := LookupOperation(operation)
+	if !ok {
+		return DefaultOperationTime
 	}
 
-	if val := os.Getenv(envVar); val != "" {
+	if val := os.Getenv(spec.TimeEnv); spec.TimeEnv != "" && val != "" {
 		if ms, err := strconv.ParseInt(val, 10, 64); err == nil {
 			return ms
 		}
 	}
-	return 1000
+	return spec.DefaultTime
 }
 
-func (tm *TaskManager) CreateExpression(exprStr string) (string, error) {
-	id := tm.generateID()
+// resolveMode проверяет режим вычисления и точность из запроса
+func resolveMode(req models.CalculateRequest) (models.CalculationMode, int, error) {
+	switch req.Mode {
+	case "", models.ModeFloat:
+		return models.ModeFloat, 0, nil
+	case models.ModeDecimal:
+		if req.Scale == 0 {
+			return models.ModeDecimal, DefaultDecimalScale, nil
+		}
+		if req.Scale < 0 || req.Scale > MaxDecimalScale {
+			return "", 0, fmt.Errorf("scale must be between 1 and %d", MaxDecimalScale)
+		}
+		return models.ModeDecimal, req.Scale, nil
+	case models.ModeRational:
+		return models.ModeRational, 0, nil
+	default:
+		return "", 0, fmt.Errorf("%w: %s", ErrUnsupportedMode, req.Mode)
+	}
+}
 
-	ast, err := ParseExpression(exprStr)
+func (tm *TaskManager) CreateExpression(req models.CalculateRequest) (string, error) {
+	mode, scale, err := resolveMode(req)
 	if err != nil {
 		return "", err
 	}
-	tm.expressionASTs[id] = ast
 
+	id := tm.generateID()
+
+	ast, err := ParseExpressionFor(req.Expression, mode)
+	if err != nil {
+		return "", err
+	}
+	ast, err = BindVariables(ast, req.Variables)
+	if err != nil {
+		return "", err
+	}
 	expression := models.Expression{
-		ID:     id,
-		Input:  exprStr,
-		Status: models.StatusProcessing,
+		ID:        id,
+		Input:     req.Expression,
+		Status:    models.StatusProcessing,
+		Variables: req.Variables,
+		Mode:      mode,
+		Scale:     scale,
+		OwnerID:   req.OwnerID,
 	}
-	tm.expressions.Store(id, expression)
+	if ast.Token.Type == Number {
+		// выражение свелось к литералу, вычислять нечего
+		result, value, err := tm.evaluateAST(ast, expression)
+		if err != nil {
+			return "", err
+		}
+		expression.Status = models.StatusCompleted
+		setExpressionResult(&expression, result, value)
+	}
+
+	// новое выражение сохраняется сразу и вне блокировки: клиент должен
+	// узнать о сбое, а других записей этого выражения ещё нет
+	if tm.expressionRepo != nil {
+		if err := tm.expressionRepo.SaveExpression(context.Background(), &expression); err != nil {
+			return "", fmt.Errorf("%w: failed to save expression: %w", ErrStorage, err)
+		}
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
 
-	tm.createTasks(ast, id)
+	tm.expressionASTs[id] = ast
+	tm.expressions[id] = expression
+	tm.createTasks(ast, expression)
 
 	return id, nil
 }
 
-func (tm *TaskManager) createTasks(node *Node, exprID string) {
+// createTasks создаёт задачи в порядке обхода дерева снизу вверх. Задачи без
+// ссылок на другие задачи сразу попадают в очередь, остальные ждут аргументов.
+// Вызывается под tm.mu.
+func (tm *TaskManager) createTasks(node *Node, expr models.Expression) {
+	walkPostOrder(node, func(node *Node) {
+		task := tm.newTask(node, expr)
+		tm.enqueue(task)
+	})
+}
+
+// walkPostOrder вызывает visit для узлов-операций, начиная с листьев
+func walkPostOrder(node *Node, visit func(*Node)) {
 	if node == nil {
 		return
 	}
+	for _, operand := range nodeOperands(node) {
+		walkPostOrder(operand, visit)
+	}
+	if node.Token.Type == Operator || node.Token.Type == UnaryOperator || node.Token.Type == Function {
+		visit(node)
+	}
+}
 
-	tm.createTasks(node.Left, exprID)
-	tm.createTasks(node.Right, exprID)
-
-	if node.Token.Type == Operator {
-		taskID := tm.generateID()
-		node.TaskID = taskID
+// newTask создаёт задачу для узла и привязывает её к нему. Вызывается под tm.mu.
+func (tm *TaskManager) newTask(node *Node, expr models.Expression) models.Task {
+	operation := nodeOperation(node)
+	task := models.Task{
+		ID:            tm.generateID(),
+		Operation:     operation,
+		OperationTime: getOperationTime(operation),
+		Mode:          expr.Mode,
+		Scale:         expr.Scale,
+		ExpressionID:  expr.ID,
+	}
+	node.TaskID = task.ID
+	tm.setTaskArgs(&task, node)
+	return task
+}
 
-		task := models.Task{
-			ID:            taskID,
-			Operation:     node.Token.Value,
-			OperationTime: getOperationTime(node.Token.Value),
-			ExpressionID:  exprID,
+// setTaskArgs заполняет аргументы задачи литералами, уже известными результатами
+// или ссылками на задачи операндов. Вызывается под tm.mu.
+func (tm *TaskManager) setTaskArgs(task *models.Task, node *Node) {
+	operands := nodeOperands(node)
+	args := make([]string, len(operands))
+	for i, operand := range operands {
+		args[i] = taskArg(operand)
+		if operandTask, ok := tm.tasks[operand.TaskID]; ok && operandTask.Result != nil {
+			args[i] = resultValue(operandTask)
 		}
+	}
 
-		if node.Left.Token.Type == Number {
-			task.Arg1 = node.Left.Token.Value
-		} else {
-			task.Arg1 = fmt.Sprintf("task:%s", node.Left.TaskID)
+	switch node.Token.Type {
+	case UnaryOperator:
+		task.Arg1 = args[0]
+	case Operator:
+		task.Arg1, task.Arg2 = args[0], args[1]
+	default:
+		task.Args = args
+	}
+}
+
+// enqueue сохраняет задачу и ставит её в очередь или в ожидание аргументов.
+// Вызывается под tm.mu.
+func (tm *TaskManager) enqueue(task models.Task) {
+	tm.storeTask(task)
+	if !HasUnresolvedArgs(task) {
+		tm.ready = append(tm.ready, task.ID)
+		tm.notifyReady()
+		return
+	}
+	for _, arg := range append([]string{task.Arg1, task.Arg2}, task.Args...) {
+		if dep, ok := taskRef(arg); ok {
+			tm.dependents[dep] = append(tm.dependents[dep], task.ID)
 		}
+	}
+}
+
+// resultValue возвращает результат задачи строкой для подстановки в аргументы
+func resultValue(task models.Task) string {
+	if task.Value != "" {
+		return task.Value
+	}
+	return strconv.FormatFloat(*task.Result, 'g', -1, 64)
+}
+
+// taskArg возвращает литерал или ссылку на задачу, вычисляющую узел
+func taskArg(node *Node) string {
+	if node.Token.Type == Number {
+		return node.Token.Value
+	}
+	return taskRefPrefix + node.TaskID
+}
+
+// префикс аргумента, ссылающегося на результат другой задачи
+const taskRefPrefix = "task:"
 
-		if node.Right.Token.Type == Number {
-			task.Arg2 = node.Right.Token.Value
-		} else {
-			task.Arg2 = fmt.Sprintf("task:%s", node.Right.TaskID)
+// taskRef возвращает ID задачи, на результат которой ссылается аргумент
+func taskRef(arg string) (string, bool) {
+	if !strings.HasPrefix(arg, taskRefPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(arg, taskRefPrefix), true
+}
+
+func supportsMode(modes []models.CalculationMode, mode models.CalculationMode) bool {
+	if mode == "" {
+		mode = models.ModeFloat
+	}
+	for _, m := range modes {
+		if m == mode {
+			return true
 		}
+	}
+	return false
+}
 
-		tm.tasks.Store(taskID, task)
-		tm.taskQueue <- task
+// HasUnresolvedArgs сообщает, ждёт ли задача результатов других задач
+func HasUnresolvedArgs(task models.Task) bool {
+	for _, arg := range append([]string{task.Arg1, task.Arg2}, task.Args...) {
+		if _, ok := taskRef(arg); ok {
+			return true
+		}
 	}
+	return false
 }
 
 func (tm *TaskManager) GetNextTask() (*models.Task, bool) {
-	select {
-	case task := <-tm.taskQueue:
-		if strings.HasPrefix(task.Arg1, "task:") || strings.HasPrefix(task.Arg2, "task:") {
-			tm.taskQueue <- task
-			return nil, false
+	return tm.GetNextTaskFor(SupportedModes())
+}
+
+// GetNextTaskFor выдаёт в аренду первую готовую задачу в одном из режимов,
+// которые поддерживает агент. Задачи с истёкшей арендой выдаются повторно,
+// задачи уже завершившихся с ошибкой выражений отбрасываются.
+func (tm *TaskManager) GetNextTaskFor(modes []models.CalculationMode) (*models.Task, bool) {
+	return tm.GetNextTaskForAgent("", modes)
+}
+
+// GetNextTaskForAgent работает как GetNextTaskFor и записывает агента в аренду,
+// чтобы при его исключении из парка задачи вернулись в очередь.
+func (tm *TaskManager) GetNextTaskForAgent(agentID string, modes []models.CalculationMode) (*models.Task, bool) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return tm.nextTask(agentID, modes)
+}
+
+// GetNextTasksForAgent выдаёт агенту в аренду до limit готовых задач за один вызов
+func (tm *TaskManager) GetNextTasksForAgent(agentID string, modes []models.CalculationMode, limit int) []models.Task {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return tm.nextTasks(agentID, modes, limit)
+}
+
+// WaitNextTaskForAgent работает как GetNextTaskForAgent, но при пустой очереди
+// ждёт готовую задачу, пока не отменён ctx
+func (tm *TaskManager) WaitNextTaskForAgent(ctx context.Context, agentID string, modes []models.CalculationMode) (*models.Task, bool) {
+	tasks := tm.WaitNextTasksForAgent(ctx, agentID, modes, 1)
+	if len(tasks) == 0 {
+		return nil, false
+	}
+	return &tasks[0], true
+}
+
+// WaitNextTasksForAgent работает как GetNextTasksForAgent, но при пустой очереди
+// ждёт хотя бы одну готовую задачу, пока не отменён ctx. Ожидающий просыпается,
+// когда задачи ставятся в очередь или истекает аренда, а не опрашивает очередь.
+func (tm *TaskManager) WaitNextTasksForAgent(ctx context.Context, agentID string, modes []models.CalculationMode, limit int) []models.Task {
+	for {
+		tm.mu.Lock()
+		tasks := tm.nextTasks(agentID, modes, limit)
+		signal := tm.readySignal
+		wake, hasLeases := tm.nextLeaseDeadline()
+		tm.mu.Unlock()
+		if len(tasks) > 0 {
+			return tasks
+		}
+
+		var timer *time.Timer
+		var expired <-chan time.Time
+		if hasLeases {
+			timer = time.NewTimer(wake.Sub(tm.now()) + time.Millisecond)
+			expired = timer.C
+		}
+		select {
+		case <-signal:
+		case <-expired:
+		case <-ctx.Done():
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// notifyReady будит ожидающих задачу. Вызывается под tm.mu.
+func (tm *TaskManager) notifyReady() {
+	close(tm.readySignal)
+	tm.readySignal = make(chan struct{})
+}
+
+// nextTasks выдаёт до limit подходящих задач из очереди. Вызывается под tm.mu.
+func (tm *TaskManager) nextTasks(agentID string, modes []models.CalculationMode, limit int) []models.Task {
+	var tasks []models.Task
+	for len(tasks) < limit {
+		task, ok := tm.nextTask(agentID, modes)
+		if !ok {
+			break
+		}
+		tasks = append(tasks, *task)
+	}
+	return tasks
+}
+
+// nextTask выдаёт первую подходящую задачу из очереди. Вызывается под tm.mu.
+func (tm *TaskManager) nextTask(agentID string, modes []models.CalculationMode) (*models.Task, bool) {
+	tm.reapExpiredLeases()
+
+	for i := 0; i < len(tm.ready); {
+		task := tm.tasks[tm.ready[i]]
+		if expr, ok := tm.expressions[task.ExpressionID]; ok && isFinished(expr.Status) {
+			tm.ready = append(tm.ready[:i], tm.ready[i+1:]...)
+			continue
 		}
+		if !supportsMode(modes, task.Mode) {
+			i++
+			continue
+		}
+		tm.ready = append(tm.ready[:i], tm.ready[i+1:]...)
+		tm.issueLease(&task, agentID)
 		return &task, true
-	default:
-		return nil, false
 	}
+	return nil, false
+}
+
+func isFinished(status models.ExpressionStatus) bool {
+	return status == models.StatusCompleted || status == models.StatusError || status == models.StatusCancelled
 }
 
 func (tm *TaskManager) UpdateTaskResult(result models.TaskResult) error {
-	taskInterface, exists := tm.tasks.Load(result.ID)
+	return tm.UpdateTaskResultForAgent("", result)
+}
+
+// UpdateTaskResultForAgent принимает результат, только если аренда задачи
+// выдана агенту agentID; пустой agentID — агент не проверялся
+func (tm *TaskManager) UpdateTaskResultForAgent(agentID string, result models.TaskResult) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	task, exists := tm.tasks[result.ID]
 	if !exists {
-		return fmt.Errorf("task not found: %s", result.ID)
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, result.ID)
+	}
+	if task.Result != nil || task.Error != nil {
+		return fmt.Errorf("%w: %s", ErrTaskCompleted, result.ID)
 	}
 
-	task := taskInterface.(models.Task)
+	tm.reapExpiredLeases()
+	if err := tm.checkLease(result.ID, result.LeaseID, agentID); err != nil {
+		return err
+	}
+	tm.releaseTask(result.ID)
+	if expr, ok := tm.expressions[task.ExpressionID]; ok && expr.Status == models.StatusCancelled {
+		log.Printf("Discarding result of task %s: expression %s was cancelled", result.ID, task.ExpressionID)
+		return nil
+	}
 
 	if result.Error != nil {
 		task.Error = result.Error
-		task.Result = nil
-		tm.tasks.Store(result.ID, task)
-
-		if task.ExpressionID != "" {
-			if exprVal, ok := tm.expressions.Load(task.ExpressionID); ok {
-				exprToUpdate := exprVal.(models.Expression)
-				if exprToUpdate.Status != models.StatusCompleted && exprToUpdate.Status != models.StatusError {
-					exprToUpdate.Status = models.StatusError
-					exprToUpdate.ErrorMsg = *result.Error
-					tm.expressions.Store(task.ExpressionID, exprToUpdate)
-					log.Printf("Expression %s failed due to task %s error: %s", task.ExpressionID, result.ID, *result.Error)
-				}
-			}
-		}
+		tm.storeTask(task)
+		// зависимые задачи уже не получат аргумент
+		delete(tm.dependents, result.ID)
+		tm.failExpression(task.ExpressionID, *result.Error)
+		log.Printf("Expression %s failed due to task %s error: %s", task.ExpressionID, result.ID, *result.Error)
 		return nil
 	}
 
 	task.Result = &result.Result
-	task.Error = nil
-	tm.tasks.Store(result.ID, task)
-
-	tm.checkAndUpdateExpressions()
+	task.Value = result.Value
+	tm.storeTask(task)
 
+	tm.resolveDependents(task)
+	tm.completeExpression(task)
 	return nil
 }
 
-func (tm *TaskManager) checkAndUpdateExpressions() {
-	var expressionsToComplete []string
-
-	tm.expressions.Range(func(exprKey, exprValue interface{}) bool {
-		exprID := exprKey.(string)
-		expr := exprValue.(models.Expression)
+// resolveDependents подставляет результат задачи в аргументы ожидающих её задач
+// и ставит в очередь те, у которых не осталось неизвестных аргументов
+func (tm *TaskManager) resolveDependents(task models.Task) {
+	value := resultValue(task)
+	ref := taskRefPrefix + task.ID
 
-		if expr.Status == models.StatusCompleted || expr.Status == models.StatusError {
-			return true
+	for _, depID := range tm.dependents[task.ID] {
+		dep := tm.tasks[depID]
+		if dep.Arg1 == ref {
+			dep.Arg1 = value
+		}
+		if dep.Arg2 == ref {
+			dep.Arg2 = value
+		}
+		for i, arg := range dep.Args {
+			if arg == ref {
+				dep.Args[i] = value
+			}
 		}
+		tm.storeTask(dep)
 
-		astRootNode, astExists := tm.expressionASTs[exprID]
-		if !astExists {
-			log.Printf("AST not found for expression %s during checkAndUpdateExpressions, cannot check completion status.", exprID)
-			return true
+		if !HasUnresolvedArgs(dep) {
+			tm.ready = append(tm.ready, depID)
+			tm.notifyReady()
 		}
+	}
+	delete(tm.dependents, task.ID)
+}
 
-		finalCalcResult, calcErr := tm.evaluateAST(astRootNode)
+// completeExpression завершает выражение, если задача вычисляла корень его дерева
+func (tm *TaskManager) completeExpression(task models.Task) {
+	expr, ok := tm.expressions[task.ExpressionID]
+	root, astExists := tm.expressionASTs[task.ExpressionID]
+	if !ok || !astExists || root.TaskID != task.ID || isFinished(expr.Status) {
+		return
+	}
 
-		if calcErr != nil {
-			if calcErr.Error() == "task_not_ready" {
-			} else {
-				if expr.Status != models.StatusError {
-					expr.Status = models.StatusError
-					expr.ErrorMsg = calcErr.Error()
-					tm.expressions.Store(exprID, expr)
-					log.Printf("Expression %s marked as ERROR during check: %s", exprID, calcErr.Error())
-				}
-			}
-		} else if finalCalcResult != nil {
-			if expr.Status != models.StatusError {
-				expr.Result = finalCalcResult
-				expr.Status = models.StatusCompleted
-				tm.expressions.Store(exprID, expr)
-				expressionsToComplete = append(expressionsToComplete, exprID)
-			}
-		}
-		return true
-	})
+	result, value, err := tm.evaluateAST(root, expr)
+	if err != nil {
+		tm.failExpression(expr.ID, err.Error())
+		log.Printf("Expression %s marked as ERROR: %s", expr.ID, err)
+		return
+	}
 
-	for _, exprID := range expressionsToComplete {
-		if exprVal, ok := tm.expressions.Load(exprID); ok {
-			expr := exprVal.(models.Expression)
-			if expr.Status == models.StatusCompleted && expr.Result != nil {
-				log.Printf("Expression %s COMPLETED with result: %f", exprID, *expr.Result)
-			}
+	expr.Status = models.StatusCompleted
+	setExpressionResult(&expr, result, value)
+	tm.storeExpression(expr)
+	log.Printf("Expression %s COMPLETED with result: %s", expr.ID, formatExpressionResult(expr))
+}
+
+// CancelExpression отменяет выражение владельца: его задачи убираются из очереди,
+// а результаты уже выданных задач отбрасываются. Аренда выданной задачи
+// остаётся до результата или истечения срока, чтобы снять её мог только
+// держатель. Чужое выражение считается ненайденным.
+func (tm *TaskManager) CancelExpression(id string, ownerID uint) (*models.Expression, error) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	expr, ok := tm.expressions[id]
+	if !ok || expr.OwnerID != ownerID {
+		return nil, fmt.Errorf("%w: %s", ErrExpressionNotFound, id)
+	}
+	if expr.Status == models.StatusCancelled {
+		return &expr, nil
+	}
+	if isFinished(expr.Status) {
+		return nil, fmt.Errorf("%w: %s", ErrExpressionFinished, id)
+	}
+
+	expr.Status = models.StatusCancelled
+	tm.storeExpression(expr)
+
+	ready := tm.ready[:0]
+	for _, taskID := range tm.ready {
+		if tm.tasks[taskID].ExpressionID != id {
+			ready = append(ready, taskID)
 		}
 	}
+	tm.ready = ready
+	// задачи отменённого выражения больше не выдаются и не ждут аргументов
+	for taskID, task := range tm.tasks {
+		if task.ExpressionID == id {
+			delete(tm.attempts, taskID)
+			delete(tm.dependents, taskID)
+		}
+	}
+
+	log.Printf("Expression %s CANCELLED by user %d", id, ownerID)
+	return &expr, nil
+}
+
+func (tm *TaskManager) failExpression(id string, errMsg string) {
+	expr, ok := tm.expressions[id]
+	if !ok || isFinished(expr.Status) {
+		return
+	}
+	expr.Status = models.StatusError
+	expr.ErrorMsg = errMsg
+	tm.storeExpression(expr)
+}
+
+// setExpressionResult сохраняет результат выражения в представлении его режима.
+// В точных режимах Result — приближение Value; если оно не помещается
+// в float64, Result остаётся пустым.
+func setExpressionResult(expr *models.Expression, result *float64, value string) {
+	expr.Result = result
+	if expr.Mode == models.ModeFloat || expr.Mode == "" {
+		return
+	}
+	expr.Result = approximate(value)
+	expr.Value = value
+	if expr.Mode == models.ModeRational {
+		expr.Fraction = NewFraction(value)
+	}
+}
+
+// formatExpressionResult возвращает результат выражения строкой для журнала
+func formatExpressionResult(expr models.Expression) string {
+	if expr.Value != "" {
+		return expr.Value
+	}
+	return strconv.FormatFloat(*expr.Result, 'f', 6, 64)
 }
 
-func (tm *TaskManager) evaluateAST(node *Node) (*float64, error) {
+// evaluateAST возвращает результат узла и, в точных режимах, его значение строкой
+func (tm *TaskManager) evaluateAST(node *Node, expr models.Expression) (*float64, string, error) {
 	if node.Token.Type == Number {
-		val, err := strconv.ParseFloat(node.Token.Value, 64)
+		ar, err := NewArithmetic(expr.Mode, expr.Scale)
 		if err != nil {
-			return nil, fmt.Errorf("invalid number in AST: %s", node.Token.Value)
+			return nil, "", err
 		}
-		return &val, nil
+		val, err := ar.Parse(node.Token.Value)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid number in AST: %s", node.Token.Value)
+		}
+		result := val.Float64()
+		return &result, ar.Format(val), nil
 	}
 
-	if node.Token.Type == Operator {
-		taskInterface, taskExists := tm.tasks.Load(node.TaskID)
+	if node.Token.Type == Operator || node.Token.Type == UnaryOperator || node.Token.Type == Function {
+		task, taskExists := tm.tasks[node.TaskID]
 		if !taskExists {
-			return nil, fmt.Errorf("task_not_ready")
+			return nil, "", fmt.Errorf("task_not_ready")
 		}
-		task := taskInterface.(models.Task)
 
 		if task.Error != nil {
-			return nil, fmt.Errorf(*task.Error)
+			return nil, "", errors.New(*task.Error)
 		}
 
 		if task.Result == nil {
-			return nil, fmt.Errorf("task_not_ready")
+			return nil, "", fmt.Errorf("task_not_ready")
 		}
-		return task.Result, nil
+		return task.Result, task.Value, nil
 	}
-	return nil, fmt.Errorf("unknown node type in AST: %v", node.Token.Type)
+	return nil, "", fmt.Errorf("unknown node type in AST: %v", node.Token.Type)
 }
 
 func (tm *TaskManager) GetExpression(id string) (*models.Expression, bool) {
-	if expr, ok := tm.expressions.Load(id); ok {
-		expression := expr.(models.Expression)
-		return &expression, true
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if expr, ok := tm.expressions[id]; ok {
+		return &expr, true
 	}
 	return nil, false
 }
 
+// GetUserExpression возвращает выражение, только если оно принадлежит пользователю
+func (tm *TaskManager) GetUserExpression(id string, ownerID uint) (*models.Expression, bool) {
+	expr, ok := tm.GetExpression(id)
+	if !ok || expr.OwnerID != ownerID {
+		return nil, false
+	}
+	return expr, true
+}
+
+// GetUserExpressions возвращает выражения пользователя в порядке создания
+func (tm *TaskManager) GetUserExpressions(ownerID uint) []models.Expression {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	expressions := []models.Expression{}
+	for _, expr := range tm.expressions {
+		if expr.OwnerID == ownerID {
+			expressions = append(expressions, expr)
+		}
+	}
+	sort.Slice(expressions, func(i, j int) bool { return idLess(expressions[i].ID, expressions[j].ID) })
+	return expressions
+}
+
 func (tm *TaskManager) GetAllExpressions() []models.Expression {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
 	var expressions []models.Expression
-	tm.expressions.Range(func(key, value interface{}) bool {
-		expressions = append(expressions, value.(models.Expression))
-		return true
-	})
+	for _, expr := range tm.expressions {
+		expressions = append(expressions, expr)
+	}
 	return expressions
 }
 
-func (tm *TaskManager) StartInternalWorker() {
+// StartInternalWorker вычисляет задачи внутри процесса. stop прекращает
+// выдачу задач воркеру и ждёт, пока он вернёт результат текущей.
+func (tm *TaskManager) StartInternalWorker() (stop func()) {
 	log.Println("Starting TaskManager internal worker...")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
 	go func() {
+		defer close(done)
 		for {
-			taskFromQueue, ok := tm.GetNextTask()
+			taskFromQueue, ok := tm.WaitNextTaskForAgent(ctx, "", SupportedModes())
 			if !ok {
-				time.Sleep(100 * time.Millisecond)
+				if ctx.Err() != nil {
+					return
+				}
 				continue
 			}
 			task := *taskFromQueue
 
 			log.Printf("Internal worker picked up task ID: %s, ExprID: %s (%s %s %s)", task.ID, task.ExpressionID, task.Arg1, task.Operation, task.Arg2)
 
-			arg1, err1 := strconv.ParseFloat(task.Arg1, 64)
-			arg2, err2 := strconv.ParseFloat(task.Arg2, 64)
-
-			taskResult := models.TaskResult{ID: task.ID}
-
-			if err1 != nil || err2 != nil {
-				errMsg := fmt.Sprintf("Error parsing arguments for task %s (ExprID: %s): %v, %v.", task.ID, task.ExpressionID, err1, err2)
-				log.Printf(errMsg)
-				errorStr := errMsg
-				taskResult.Error = &errorStr
-			} else {
-				var resultValue float64
-				var calcError error
-
-				switch task.Operation {
-				case "+":
-					resultValue = arg1 + arg2
-				case "-":
-					resultValue = arg1 - arg2
-				case "*":
-					resultValue = arg1 * arg2
-				case "/":
-					if arg2 == 0 {
-						calcError = fmt.Errorf("division by zero")
-					} else {
-						resultValue = arg1 / arg2
-					}
-				default:
-					calcError = fmt.Errorf("unknown operation: %s", task.Operation)
-				}
-
-				if calcError != nil {
-					log.Printf("Error calculating task %s (ExprID: %s): %v", task.ID, task.ExpressionID, calcError)
-					errorStr := calcError.Error()
-					taskResult.Error = &errorStr
-				} else {
-					taskResult.Result = resultValue
-				}
+			taskResult := EvaluateTask(task)
+			if taskResult.Error != nil {
+				log.Printf("Error calculating task %s (ExprID: %s): %s", task.ID, task.ExpressionID, *taskResult.Error)
 			}
 
 			if err := tm.UpdateTaskResult(taskResult); err != nil {
@@ -316,4 +681,8 @@ func (tm *TaskManager) StartInternalWorker() {
 		}
 	}()
 	log.Println("TaskManager internal worker started.")
+	return func() {
+		cancel()
+		<-done
+	}
 }
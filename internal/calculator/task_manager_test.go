@@ -1,6 +1,10 @@
 package calculator
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -43,7 +47,7 @@ func TestTaskManager_CreateExpression(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tm := NewTaskManager()
-			id, err := tm.CreateExpression(tt.expr)
+			id, err := tm.CreateExpression(models.CalculateRequest{Expression: tt.expr})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CreateExpression() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -57,7 +61,7 @@ func TestTaskManager_CreateExpression(t *testing.T) {
 
 func TestTaskManager_GetNextTask(t *testing.T) {
 	tm := NewTaskManager()
-	_, err := tm.CreateExpression("2+2")
+	_, err := tm.CreateExpression(models.CalculateRequest{Expression: "2+2"})
 	if err != nil {
 		t.Fatalf("Failed to create expression: %v", err)
 	}
@@ -79,7 +83,7 @@ func TestTaskManager_GetNextTask(t *testing.T) {
 func TestTaskManager_UpdateTaskResult(t *testing.T) {
 	tm := NewTaskManager()
 
-	id, err := tm.CreateExpression("2+2")
+	id, err := tm.CreateExpression(models.CalculateRequest{Expression: "2+2"})
 	if err != nil {
 		t.Fatalf("Failed to create expression: %v", err)
 	}
@@ -90,8 +94,9 @@ func TestTaskManager_UpdateTaskResult(t *testing.T) {
 	}
 
 	result := models.TaskResult{
-		ID:     task.ID,
-		Result: 4,
+		ID:      task.ID,
+		LeaseID: task.LeaseID,
+		Result:  4,
 	}
 
 	err = tm.UpdateTaskResult(result)
@@ -117,7 +122,7 @@ func TestTaskManager_UpdateTaskResult(t *testing.T) {
 
 func TestTaskManager_ComplexExpression(t *testing.T) {
 	tm := NewTaskManager()
-	id, err := tm.CreateExpression("2+2*2")
+	id, err := tm.CreateExpression(models.CalculateRequest{Expression: "2+2*2"})
 	if err != nil {
 		t.Fatalf("Failed to create expression: %v", err)
 	}
@@ -143,8 +148,9 @@ func TestTaskManager_ComplexExpression(t *testing.T) {
 		}
 
 		err = tm.UpdateTaskResult(models.TaskResult{
-			ID:     task.ID,
-			Result: result,
+			ID:      task.ID,
+			LeaseID: task.LeaseID,
+			Result:  result,
 		})
 		if err != nil {
 			t.Fatalf("UpdateTaskResult() error = %v", err)
@@ -166,3 +172,551 @@ func TestTaskManager_ComplexExpression(t *testing.T) {
 		t.Errorf("Expression result = %v, want 6", expr.Result)
 	}
 }
+
+func TestTaskManager_UnaryMinus(t *testing.T) {
+	tm := NewTaskManager()
+	id, err := tm.CreateExpression(models.CalculateRequest{Expression: "-(1+2)"})
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+
+	task, ok := tm.GetNextTask()
+	if !ok {
+		t.Fatalf("GetNextTask() returned no task")
+	}
+	if task.Operation != "+" || task.Arg1 != "1" || task.Arg2 != "2" {
+		t.Fatalf("Unexpected first task: %+v", task)
+	}
+
+	if err := tm.UpdateTaskResult(models.TaskResult{ID: task.ID, LeaseID: task.LeaseID, Result: 3}); err != nil {
+		t.Fatalf("UpdateTaskResult() error = %v", err)
+	}
+
+	negTask, ok := tm.GetNextTask()
+	if !ok {
+		t.Fatalf("negation task was not queued")
+	}
+	if negTask.Operation != OpNegate || negTask.Arg1 != "3" {
+		t.Errorf("Negation task = %+v, want neg of substituted 3", negTask)
+	}
+
+	expr, _ := tm.GetExpression(id)
+	if expr.Status != models.StatusProcessing {
+		t.Errorf("Expression status = %v, want %v", expr.Status, models.StatusProcessing)
+	}
+}
+
+func TestTaskManager_NegativeLiteral(t *testing.T) {
+	tm := NewTaskManager()
+	id, err := tm.CreateExpression(models.CalculateRequest{Expression: "-5"})
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+
+	if _, ok := tm.GetNextTask(); ok {
+		t.Errorf("GetNextTask() returned a task for a literal expression")
+	}
+
+	expr, ok := tm.GetExpression(id)
+	if !ok {
+		t.Fatalf("GetExpression() returned no expression")
+	}
+	if expr.Status != models.StatusCompleted || expr.Result == nil || *expr.Result != -5 {
+		t.Errorf("Expression = %+v, want completed with -5", expr)
+	}
+}
+
+func TestTaskManager_FunctionCall(t *testing.T) {
+	tm := NewTaskManager()
+	id, err := tm.CreateExpression(models.CalculateRequest{Expression: "max(1, 5, 3)"})
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+
+	task, ok := tm.GetNextTask()
+	if !ok {
+		t.Fatalf("GetNextTask() returned no task")
+	}
+	if task.Operation != "max" || len(task.Args) != 3 || task.Args[1] != "5" {
+		t.Fatalf("Unexpected task: %+v", task)
+	}
+
+	if err := tm.UpdateTaskResult(EvaluateTask(*task)); err != nil {
+		t.Fatalf("UpdateTaskResult() error = %v", err)
+	}
+
+	expr, ok := tm.GetExpression(id)
+	if !ok {
+		t.Fatalf("GetExpression() returned no expression")
+	}
+	if expr.Status != models.StatusCompleted || expr.Result == nil || *expr.Result != 5 {
+		t.Errorf("Expression = %+v, want completed with 5", expr)
+	}
+}
+
+func TestTaskManager_Variables(t *testing.T) {
+	tm := NewTaskManager()
+	id, err := tm.CreateExpression(models.CalculateRequest{
+		Expression: "price * qty",
+		Variables:  map[string]float64{"price": 2.5, "qty": 4},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+
+	task, ok := tm.GetNextTask()
+	if !ok {
+		t.Fatalf("GetNextTask() returned no task")
+	}
+	if task.Operation != "*" || task.Arg1 != "2.5" || task.Arg2 != "4" {
+		t.Errorf("Unexpected task: %+v", task)
+	}
+
+	expr, ok := tm.GetExpression(id)
+	if !ok {
+		t.Fatalf("GetExpression() returned no expression")
+	}
+	if expr.Variables["qty"] != 4 {
+		t.Errorf("Expression variables = %v, want qty=4", expr.Variables)
+	}
+}
+
+func TestTaskManager_UnboundVariable(t *testing.T) {
+	tm := NewTaskManager()
+	_, err := tm.CreateExpression(models.CalculateRequest{
+		Expression: "price * qty",
+		Variables:  map[string]float64{"price": 2.5},
+	})
+	if !errors.Is(err, ErrUnboundVariable) {
+		t.Fatalf("CreateExpression() error = %v, want %v", err, ErrUnboundVariable)
+	}
+	if !strings.Contains(err.Error(), "qty") {
+		t.Errorf("CreateExpression() error = %v, want it to name the variable", err)
+	}
+}
+
+// runTasks вычисляет все готовые задачи, пока очередь не опустеет
+func runTasks(t *testing.T, tm *TaskManager) int {
+	t.Helper()
+	count := 0
+	for {
+		task, ok := tm.GetNextTask()
+		if !ok {
+			return count
+		}
+		if HasUnresolvedArgs(*task) {
+			t.Fatalf("GetNextTask() returned a task with unresolved arguments: %+v", task)
+		}
+		if err := tm.UpdateTaskResult(EvaluateTask(*task)); err != nil {
+			t.Fatalf("UpdateTaskResult() error = %v", err)
+		}
+		count++
+	}
+}
+
+func TestTaskManager_ReadyQueue(t *testing.T) {
+	tm := NewTaskManager()
+	id, err := tm.CreateExpression(models.CalculateRequest{Expression: "(1+2)*(3+4)"})
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+
+	first, _ := tm.GetNextTask()
+	second, _ := tm.GetNextTask()
+	if first == nil || second == nil || first.Operation != "+" || second.Operation != "+" {
+		t.Fatalf("Expected both additions to be ready first, got %+v and %+v", first, second)
+	}
+	if _, ok := tm.GetNextTask(); ok {
+		t.Fatalf("GetNextTask() returned the multiplication before its arguments were known")
+	}
+
+	tm.UpdateTaskResult(EvaluateTask(*first))
+	if _, ok := tm.GetNextTask(); ok {
+		t.Fatalf("GetNextTask() returned the multiplication with one argument missing")
+	}
+	tm.UpdateTaskResult(EvaluateTask(*second))
+
+	mul, ok := tm.GetNextTask()
+	if !ok {
+		t.Fatalf("multiplication was not promoted to the ready queue")
+	}
+	if mul.Operation != "*" || mul.Arg1 != "3" || mul.Arg2 != "7" {
+		t.Errorf("Multiplication task = %+v, want 3 * 7", mul)
+	}
+	tm.UpdateTaskResult(EvaluateTask(*mul))
+
+	expr, _ := tm.GetExpression(id)
+	if expr.Status != models.StatusCompleted || expr.Result == nil || *expr.Result != 21 {
+		t.Errorf("Expression = %+v, want completed with 21", expr)
+	}
+}
+
+func TestTaskManager_LargeExpression(t *testing.T) {
+	input := "1" + strings.Repeat("+1", 250) + "*max(2, 3-1)"
+
+	tm := NewTaskManager()
+	done := make(chan struct{})
+	var id string
+	var err error
+	go func() {
+		id, err = tm.CreateExpression(models.CalculateRequest{Expression: input})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("CreateExpression() blocked on a large expression")
+	}
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+
+	if n := runTasks(t, tm); n != 253 {
+		t.Errorf("evaluated %d tasks, want 253", n)
+	}
+
+	want, _ := Calc(input)
+	expr, _ := tm.GetExpression(id)
+	if expr.Status != models.StatusCompleted || expr.Result == nil || *expr.Result != want {
+		t.Errorf("Expression = %+v, want completed with %v", expr, want)
+	}
+}
+
+func TestTaskManager_FailedExpressionDropsTasks(t *testing.T) {
+	tm := NewTaskManager()
+	id, err := tm.CreateExpression(models.CalculateRequest{Expression: "1/0 + (2+3)"})
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+
+	task, ok := tm.GetNextTask()
+	if !ok || task.Operation != "/" {
+		t.Fatalf("Expected division first, got %+v", task)
+	}
+	if err := tm.UpdateTaskResult(EvaluateTask(*task)); err != nil {
+		t.Fatalf("UpdateTaskResult() error = %v", err)
+	}
+
+	if task, ok := tm.GetNextTask(); ok {
+		t.Errorf("GetNextTask() returned %+v from a failed expression", task)
+	}
+	expr, _ := tm.GetExpression(id)
+	if expr.Status != models.StatusError || expr.ErrorMsg != "division by zero" {
+		t.Errorf("Expression = %+v, want error division by zero", expr)
+	}
+}
+
+func TestTaskManager_OutOfRange(t *testing.T) {
+	tm := NewTaskManager()
+	id, err := tm.CreateExpression(models.CalculateRequest{Expression: "10^400"})
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+
+	task, ok := tm.GetNextTask()
+	if !ok {
+		t.Fatalf("GetNextTask() returned no task")
+	}
+	result := EvaluateTask(*task)
+	if result.Error == nil {
+		t.Fatalf("EvaluateTask() = %+v, want out of range error", result)
+	}
+	if _, err := json.Marshal(result); err != nil {
+		t.Fatalf("json.Marshal(result) error = %v", err)
+	}
+	if err := tm.UpdateTaskResult(result); err != nil {
+		t.Fatalf("UpdateTaskResult() error = %v", err)
+	}
+
+	expr, _ := tm.GetExpression(id)
+	if expr.Status != models.StatusError || !strings.Contains(expr.ErrorMsg, "out of range") {
+		t.Errorf("Expression = %+v, want out of range error", expr)
+	}
+	if _, err := json.Marshal(tm.GetAllExpressions()); err != nil {
+		t.Errorf("json.Marshal(expressions) error = %v", err)
+	}
+}
+
+func TestTaskManager_EvaluateASTTaskError(t *testing.T) {
+	tm := NewTaskManager()
+	ast, err := ParseExpression("2+2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// текст ошибки задачи приходит от агента и не должен читаться как формат
+	errMsg := "100% of %d workers failed"
+	ast.TaskID = "1"
+	tm.tasks["1"] = models.Task{ID: "1", Error: &errMsg}
+
+	if _, _, err := tm.evaluateAST(ast, models.Expression{}); err == nil || err.Error() != errMsg {
+		t.Errorf("evaluateAST() error = %v, want %q", err, errMsg)
+	}
+}
+
+func TestTaskManager_UpdateTaskResultErrors(t *testing.T) {
+	tm := NewTaskManager()
+	if _, err := tm.CreateExpression(models.CalculateRequest{Expression: "2+2"}); err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+	task, _ := tm.GetNextTask()
+
+	if err := tm.UpdateTaskResult(models.TaskResult{ID: "missing"}); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("UpdateTaskResult() error = %v, want %v", err, ErrTaskNotFound)
+	}
+	if err := tm.UpdateTaskResult(models.TaskResult{ID: task.ID, LeaseID: task.LeaseID, Result: 4}); err != nil {
+		t.Fatalf("UpdateTaskResult() error = %v", err)
+	}
+	if err := tm.UpdateTaskResult(models.TaskResult{ID: task.ID, LeaseID: task.LeaseID, Result: 5}); !errors.Is(err, ErrTaskCompleted) {
+		t.Errorf("UpdateTaskResult() error = %v, want %v", err, ErrTaskCompleted)
+	}
+}
+
+func TestTaskManager_CancelExpression(t *testing.T) {
+	tm := NewTaskManager()
+	id, err := tm.CreateExpression(models.CalculateRequest{Expression: "(1+2)*(3+4)", OwnerID: 7})
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+	other, err := tm.CreateExpression(models.CalculateRequest{Expression: "5-1", OwnerID: 7})
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+
+	inFlight, ok := tm.GetNextTask()
+	if !ok || inFlight.ExpressionID != id {
+		t.Fatalf("GetNextTask() = %+v, want a task of expression %s", inFlight, id)
+	}
+
+	if _, err := tm.CancelExpression(id, 8); !errors.Is(err, ErrExpressionNotFound) {
+		t.Fatalf("CancelExpression() by another user error = %v, want %v", err, ErrExpressionNotFound)
+	}
+
+	expr, err := tm.CancelExpression(id, 7)
+	if err != nil {
+		t.Fatalf("CancelExpression() error = %v", err)
+	}
+	if expr.Status != models.StatusCancelled {
+		t.Errorf("Expression status = %v, want %v", expr.Status, models.StatusCancelled)
+	}
+
+	// в очереди остались только задачи другого выражения
+	task, ok := tm.GetNextTask()
+	if !ok || task.ExpressionID != other {
+		t.Fatalf("GetNextTask() = %+v, want a task of expression %s", task, other)
+	}
+	if task, ok := tm.GetNextTask(); ok {
+		t.Errorf("GetNextTask() returned %+v after cancellation", task)
+	}
+	if leases := tm.Leases(); len(leases) != 2 {
+		t.Errorf("Leases() = %+v, want leases of tasks %s and %s", leases, inFlight.ID, task.ID)
+	}
+	tm.mu.Lock()
+	for taskID, cancelled := range tm.tasks {
+		if cancelled.ExpressionID != id {
+			continue
+		}
+		if _, ok := tm.dependents[taskID]; ok {
+			t.Errorf("dependents of cancelled task %s were kept", taskID)
+		}
+		if _, ok := tm.attempts[taskID]; ok {
+			t.Errorf("attempts of cancelled task %s were kept", taskID)
+		}
+	}
+	tm.mu.Unlock()
+
+	// аренду отменённой задачи снимает только её держатель
+	if err := tm.UpdateTaskResult(models.TaskResult{ID: inFlight.ID, Result: 3}); !errors.Is(err, ErrLeaseRequired) {
+		t.Errorf("UpdateTaskResult() without lease error = %v, want %v", err, ErrLeaseRequired)
+	}
+	if err := tm.UpdateTaskResult(EvaluateTask(*inFlight)); err != nil {
+		t.Fatalf("UpdateTaskResult() error = %v", err)
+	}
+	if leases := tm.Leases(); len(leases) != 1 || leases[0].TaskID != task.ID {
+		t.Errorf("Leases() = %+v, want only the lease of task %s", leases, task.ID)
+	}
+	expr, _ = tm.GetExpression(id)
+	if expr.Status != models.StatusCancelled || expr.Result != nil {
+		t.Errorf("Expression = %+v, want cancelled without result", expr)
+	}
+
+	if _, err := tm.CancelExpression(id, 7); err != nil {
+		t.Errorf("repeated CancelExpression() error = %v", err)
+	}
+}
+
+func TestTaskManager_CancelFinishedExpression(t *testing.T) {
+	tm := NewTaskManager()
+	id, err := tm.CreateExpression(models.CalculateRequest{Expression: "42"})
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+	if _, err := tm.CancelExpression(id, 0); !errors.Is(err, ErrExpressionFinished) {
+		t.Errorf("CancelExpression() error = %v, want %v", err, ErrExpressionFinished)
+	}
+	if _, err := tm.CancelExpression("missing", 0); !errors.Is(err, ErrExpressionNotFound) {
+		t.Errorf("CancelExpression() error = %v, want %v", err, ErrExpressionNotFound)
+	}
+}
+
+func TestTaskManager_UserExpressions(t *testing.T) {
+	tm := NewTaskManager()
+	var aliceIDs []string
+	for _, input := range []string{"1+1", "2", "3*3"} {
+		id, err := tm.CreateExpression(models.CalculateRequest{Expression: input, OwnerID: 1})
+		if err != nil {
+			t.Fatalf("Failed to create expression: %v", err)
+		}
+		aliceIDs = append(aliceIDs, id)
+	}
+	bobID, err := tm.CreateExpression(models.CalculateRequest{Expression: "4-4", OwnerID: 2})
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+
+	expressions := tm.GetUserExpressions(1)
+	if len(expressions) != len(aliceIDs) {
+		t.Fatalf("GetUserExpressions() returned %d expressions, want %d", len(expressions), len(aliceIDs))
+	}
+	for i, expr := range expressions {
+		if expr.ID != aliceIDs[i] {
+			t.Errorf("GetUserExpressions()[%d].ID = %s, want %s", i, expr.ID, aliceIDs[i])
+		}
+	}
+	if got := tm.GetUserExpressions(3); got == nil || len(got) != 0 {
+		t.Errorf("GetUserExpressions() for a user without expressions = %v, want empty list", got)
+	}
+
+	if _, ok := tm.GetUserExpression(bobID, 1); ok {
+		t.Errorf("GetUserExpression() returned another user's expression")
+	}
+	if expr, ok := tm.GetUserExpression(bobID, 2); !ok || expr.Input != "4-4" {
+		t.Errorf("GetUserExpression() = %+v, %v, want own expression", expr, ok)
+	}
+}
+
+func TestTaskManager_WaitNextTask(t *testing.T) {
+	tm := NewTaskManager()
+
+	// пустая очередь: ожидание заканчивается по таймауту
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if task, ok := tm.WaitNextTaskForAgent(ctx, "", SupportedModes()); ok {
+		t.Fatalf("WaitNextTaskForAgent() = %+v on empty queue", task)
+	}
+
+	// ожидающий просыпается, когда задача ставится в очередь
+	got := make(chan *models.Task, 1)
+	go func() {
+		task, _ := tm.WaitNextTaskForAgent(context.Background(), "", SupportedModes())
+		got <- task
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if _, err := tm.CreateExpression(models.CalculateRequest{Expression: "(1+2)*3"}); err != nil {
+		t.Fatal(err)
+	}
+	var first *models.Task
+	select {
+	case first = <-got:
+	case <-time.After(time.Second):
+		t.Fatal("WaitNextTaskForAgent() did not wake up after CreateExpression")
+	}
+
+	// зависимая задача будит ожидающего, когда приходит результат первой
+	go func() {
+		task, _ := tm.WaitNextTaskForAgent(context.Background(), "", SupportedModes())
+		got <- task
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if err := tm.UpdateTaskResult(EvaluateTask(*first)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case second := <-got:
+		if second.Operation != "*" || second.Arg1 != "3" {
+			t.Errorf("WaitNextTaskForAgent() = %+v, want 3*3", second)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WaitNextTaskForAgent() did not wake up after the dependency completed")
+	}
+}
+
+func TestTaskManager_WaitNextTaskLeaseExpiry(t *testing.T) {
+	t.Setenv("TIME_ADDITION_MS", "20")
+	tm := NewTaskManager()
+	tm.leaseGrace = 0
+	if _, err := tm.CreateExpression(models.CalculateRequest{Expression: "2+2"}); err != nil {
+		t.Fatal(err)
+	}
+	first, ok := tm.GetNextTask()
+	if !ok {
+		t.Fatal("GetNextTask() returned no task")
+	}
+
+	// задача возвращается ожидающему после истечения аренды без новых событий в очереди
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	second, ok := tm.WaitNextTaskForAgent(ctx, "", SupportedModes())
+	if !ok || second.ID != first.ID || second.LeaseID == first.LeaseID {
+		t.Errorf("WaitNextTaskForAgent() = %+v, %v, want task %s with a new lease", second, ok, first.ID)
+	}
+}
+
+func TestTaskManager_GetNextTasks(t *testing.T) {
+	tm := NewTaskManager()
+	if _, err := tm.CreateExpression(models.CalculateRequest{Expression: "(1+2)+(3*4)+(5*6)"}); err != nil {
+		t.Fatal(err)
+	}
+
+	first := tm.GetNextTasksForAgent("calc-1", SupportedModes(), 2)
+	if len(first) != 2 {
+		t.Fatalf("GetNextTasksForAgent(max=2) = %+v, want 2 tasks", first)
+	}
+	// в пачку попадают только готовые задачи, зависимые ждут результатов
+	rest := tm.GetNextTasksForAgent("calc-1", SupportedModes(), 10)
+	if len(rest) != 1 {
+		t.Fatalf("GetNextTasksForAgent(max=10) = %+v, want the last independent task", rest)
+	}
+	seen := make(map[string]bool)
+	for _, task := range append(first, rest...) {
+		if seen[task.ID] || task.LeaseID == "" || HasUnresolvedArgs(task) {
+			t.Errorf("unexpected task in batch: %+v", task)
+		}
+		seen[task.ID] = true
+	}
+	if leases := tm.Leases(); len(leases) != 3 || leases[0].AgentID != "calc-1" {
+		t.Errorf("Leases() = %+v, want 3 leases held by calc-1", leases)
+	}
+	if tasks := tm.GetNextTasksForAgent("calc-1", SupportedModes(), 10); len(tasks) != 0 {
+		t.Errorf("GetNextTasksForAgent() = %+v on drained queue", tasks)
+	}
+}
+
+func TestTaskManager_StopInternalWorker(t *testing.T) {
+	tm := NewTaskManager()
+	stop := tm.StartInternalWorker()
+
+	id, err := tm.CreateExpression(models.CalculateRequest{Expression: "2+2"})
+	if err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if expr, _ := tm.GetExpression(id); expr.Status == models.StatusCompleted {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("internal worker did not complete expression %s", id)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// после остановки воркер не берёт задачи
+	stop()
+	if _, err := tm.CreateExpression(models.CalculateRequest{Expression: "3+3"}); err != nil {
+		t.Fatalf("Failed to create expression: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := tm.GetNextTask(); !ok {
+		t.Errorf("GetNextTask() returned no task, want it left by the stopped worker")
+	}
+}
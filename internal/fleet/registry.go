@@ -0,0 +1,215 @@
+// Package fleet ведёт учёт удалённых агентов: регистрацию, heartbeat
+// и исключение агентов, переставших выходить на связь.
+package fleet
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+)
+
+var ErrAgentNotFound = errors.New("agent not found")
+
+// DefaultHeartbeatTTL — срок, после которого молчащий агент исключается
+const DefaultHeartbeatTTL = 15 * time.Second
+
+// HeartbeatTTLFromEnv читает срок из AGENT_HEARTBEAT_TTL_MS
+func HeartbeatTTLFromEnv() time.Duration {
+	if val := os.Getenv("AGENT_HEARTBEAT_TTL_MS"); val != "" {
+		if ms, err := strconv.ParseInt(val, 10, 64); err == nil && ms > 0 {
+			return time.Duration(ms) * time.Millisecond
+		}
+	}
+	return DefaultHeartbeatTTL
+}
+
+type Registry struct {
+	mu      sync.Mutex
+	agents  map[string]*models.AgentInfo
+	ttl     time.Duration
+	now     func() time.Time
+	onEvict func(agentID string) // вызывается вне блокировки для каждого исключённого агента
+}
+
+// NewRegistry создаёт реестр; onEvict получает ID исключённого или
+// перерегистрированного агента, чтобы вернуть его задачи в очередь.
+func NewRegistry(ttl time.Duration, onEvict func(agentID string)) *Registry {
+	if onEvict == nil {
+		onEvict = func(string) {}
+	}
+	return &Registry{
+		agents:  make(map[string]*models.AgentInfo),
+		ttl:     ttl,
+		now:     time.Now,
+		onEvict: onEvict,
+	}
+}
+
+// HeartbeatInterval — как часто агенту присылать heartbeat: три попытки
+// до истечения срока, чтобы одна потерянная не исключала агента
+func (r *Registry) HeartbeatInterval() time.Duration {
+	return r.ttl / 3
+}
+
+// Register добавляет агента. Агент без ID получает новый; повторная
+// регистрация с прежним ID означает перезапуск агента, поэтому его
+// прежние задачи возвращаются в очередь.
+func (r *Registry) Register(reg models.AgentRegistration) models.AgentInfo {
+	r.mu.Lock()
+	now := r.now()
+	if reg.ID == "" {
+		reg.ID = r.newID()
+	}
+	_, restarted := r.agents[reg.ID]
+	agent := &models.AgentInfo{
+		ID:           reg.ID,
+		Hostname:     reg.Hostname,
+		Workers:      reg.Workers,
+		Operations:   append([]string(nil), reg.Operations...),
+		Modes:        append([]models.CalculationMode(nil), reg.Modes...),
+		RegisteredAt: now,
+		LastSeen:     now,
+	}
+	r.agents[reg.ID] = agent
+	info := *agent
+	r.mu.Unlock()
+
+	if restarted {
+		log.Printf("Agent %s re-registered, releasing its previous tasks", reg.ID)
+		r.onEvict(reg.ID)
+	}
+	log.Printf("Agent %s registered: host %s, %d workers", reg.ID, reg.Hostname, reg.Workers)
+	return info
+}
+
+// newID назначает агенту случайный ID: счётчик после перезапуска оркестратора
+// выдал бы новому агенту ID, который ещё помнит прежний. Вызывается под r.mu.
+func (r *Registry) newID() string {
+	for {
+		var b [6]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			panic(fmt.Sprintf("fleet: crypto/rand failed: %v", err))
+		}
+		id := "agent-" + hex.EncodeToString(b[:])
+		if _, taken := r.agents[id]; !taken {
+			return id
+		}
+	}
+}
+
+// Heartbeat отмечает, что агент на связи
+func (r *Registry) Heartbeat(agentID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	agent, ok := r.agents[agentID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrAgentNotFound, agentID)
+	}
+	agent.LastSeen = r.now()
+	return nil
+}
+
+// Registered сообщает, что агент есть в парке: исключённый администратором
+// или по истечении срока агент должен зарегистрироваться заново
+func (r *Registry) Registered(agentID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.agents[agentID]
+	return ok
+}
+
+// Remove исключает агента по запросу администратора
+func (r *Registry) Remove(agentID string) error {
+	r.mu.Lock()
+	_, ok := r.agents[agentID]
+	delete(r.agents, agentID)
+	r.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrAgentNotFound, agentID)
+	}
+	log.Printf("Agent %s removed", agentID)
+	r.onEvict(agentID)
+	return nil
+}
+
+// Disconnect исключает агента, чья сессия оборвалась, если он не успел
+// зарегистрироваться заново: новая регистрация с тем же ID остаётся
+func (r *Registry) Disconnect(agentID string, registeredAt time.Time) bool {
+	r.mu.Lock()
+	agent, ok := r.agents[agentID]
+	if !ok || !agent.RegisteredAt.Equal(registeredAt) {
+		r.mu.Unlock()
+		return false
+	}
+	delete(r.agents, agentID)
+	r.mu.Unlock()
+
+	log.Printf("Agent %s disconnected", agentID)
+	r.onEvict(agentID)
+	return true
+}
+
+// Evict исключает агентов, не присылавших heartbeat дольше срока, и возвращает их ID
+func (r *Registry) Evict() []string {
+	r.mu.Lock()
+	now := r.now()
+	var evicted []string
+	for id, agent := range r.agents {
+		if now.Sub(agent.LastSeen) > r.ttl {
+			delete(r.agents, id)
+			evicted = append(evicted, id)
+		}
+	}
+	r.mu.Unlock()
+
+	sort.Strings(evicted)
+	for _, id := range evicted {
+		log.Printf("Agent %s missed heartbeats, evicting", id)
+		r.onEvict(id)
+	}
+	return evicted
+}
+
+// Agents возвращает живых агентов по ID; InFlight не заполняется
+func (r *Registry) Agents() []models.AgentInfo {
+	r.Evict()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	agents := make([]models.AgentInfo, 0, len(r.agents))
+	for _, agent := range r.agents {
+		agents = append(agents, *agent)
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].ID < agents[j].ID })
+	return agents
+}
+
+// StartEvictionLoop периодически исключает молчащих агентов до вызова stop
+func (r *Registry) StartEvictionLoop(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.Evict()
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
@@ -0,0 +1,127 @@
+package fleet
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+)
+
+// newTestRegistry возвращает реестр с управляемыми часами и список исключённых агентов
+func newTestRegistry(ttl time.Duration) (*Registry, *time.Time, *[]string) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var evicted []string
+	r := NewRegistry(ttl, func(id string) { evicted = append(evicted, id) })
+	r.now = func() time.Time { return now }
+	return r, &now, &evicted
+}
+
+func TestRegistry_Register(t *testing.T) {
+	r, _, evicted := newTestRegistry(time.Minute)
+
+	first := r.Register(models.AgentRegistration{Hostname: "host-a", Workers: 2})
+	second := r.Register(models.AgentRegistration{Hostname: "host-b", Workers: 4})
+	if first.ID == "" || first.ID == second.ID {
+		t.Fatalf("assigned IDs %q and %q, want distinct non-empty IDs", first.ID, second.ID)
+	}
+	named := r.Register(models.AgentRegistration{ID: "calc-1", Hostname: "host-c", Workers: 1, Operations: []string{"+", "-"}})
+	if named.ID != "calc-1" {
+		t.Errorf("Register() ID = %q, want calc-1", named.ID)
+	}
+
+	agents := r.Agents()
+	if len(agents) != 3 {
+		t.Fatalf("Agents() = %+v, want 3 agents", agents)
+	}
+	if agents[2].ID != "calc-1" || agents[2].Workers != 1 || len(agents[2].Operations) != 2 {
+		t.Errorf("Agents()[2] = %+v", agents[2])
+	}
+	if len(*evicted) != 0 {
+		t.Errorf("evicted %v on first registration", *evicted)
+	}
+
+	// перезапуск агента с тем же ID освобождает его прежние задачи
+	r.Register(models.AgentRegistration{ID: "calc-1", Hostname: "host-c", Workers: 3})
+	if len(*evicted) != 1 || (*evicted)[0] != "calc-1" {
+		t.Errorf("evicted = %v, want [calc-1] after re-registration", *evicted)
+	}
+	if agents := r.Agents(); len(agents) != 3 || agents[2].Workers != 3 {
+		t.Errorf("Agents() = %+v, want updated calc-1", agents)
+	}
+}
+
+func TestRegistry_HeartbeatAndEviction(t *testing.T) {
+	r, now, evicted := newTestRegistry(15 * time.Second)
+	alive := r.Register(models.AgentRegistration{Hostname: "alive"})
+	dead := r.Register(models.AgentRegistration{Hostname: "dead"})
+
+	*now = now.Add(10 * time.Second)
+	if err := r.Heartbeat(alive.ID); err != nil {
+		t.Fatalf("Heartbeat() error = %v", err)
+	}
+	*now = now.Add(10 * time.Second)
+
+	agents := r.Agents()
+	if len(agents) != 1 || agents[0].ID != alive.ID {
+		t.Fatalf("Agents() = %+v, want only %s", agents, alive.ID)
+	}
+	if !agents[0].LastSeen.Equal(now.Add(-10 * time.Second)) {
+		t.Errorf("LastSeen = %v, want time of the last heartbeat", agents[0].LastSeen)
+	}
+	if len(*evicted) != 1 || (*evicted)[0] != dead.ID {
+		t.Errorf("evicted = %v, want [%s]", *evicted, dead.ID)
+	}
+
+	if err := r.Heartbeat(dead.ID); !errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("Heartbeat() for evicted agent error = %v, want %v", err, ErrAgentNotFound)
+	}
+	if r.Registered(dead.ID) || !r.Registered(alive.ID) {
+		t.Errorf("Registered() = %v/%v for evicted/alive agent, want false/true", r.Registered(dead.ID), r.Registered(alive.ID))
+	}
+}
+
+func TestRegistry_Remove(t *testing.T) {
+	r, _, evicted := newTestRegistry(time.Minute)
+	agent := r.Register(models.AgentRegistration{Hostname: "host"})
+
+	if err := r.Remove(agent.ID); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if len(r.Agents()) != 0 || len(*evicted) != 1 || r.Registered(agent.ID) {
+		t.Errorf("agent %s is still registered or its tasks were not released", agent.ID)
+	}
+	if err := r.Remove(agent.ID); !errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("Remove() error = %v, want %v", err, ErrAgentNotFound)
+	}
+}
+
+func TestRegistry_HeartbeatInterval(t *testing.T) {
+	r := NewRegistry(15*time.Second, nil)
+	if got := r.HeartbeatInterval(); got != 5*time.Second {
+		t.Errorf("HeartbeatInterval() = %v, want 5s", got)
+	}
+}
+
+func TestRegistry_Disconnect(t *testing.T) {
+	r, now, evicted := newTestRegistry(time.Minute)
+	first := r.Register(models.AgentRegistration{ID: "calc-1"})
+
+	// агент переподключился раньше, чем оборвалась прежняя сессия
+	*now = now.Add(time.Second)
+	second := r.Register(models.AgentRegistration{ID: "calc-1"})
+	*evicted = nil
+	if r.Disconnect(first.ID, first.RegisteredAt) {
+		t.Error("Disconnect() removed the newer registration")
+	}
+	if len(r.Agents()) != 1 || len(*evicted) != 0 {
+		t.Fatalf("agents = %+v, evicted = %v, want calc-1 kept", r.Agents(), *evicted)
+	}
+
+	if !r.Disconnect(second.ID, second.RegisteredAt) {
+		t.Error("Disconnect() = false for the current registration")
+	}
+	if len(r.Agents()) != 0 || len(*evicted) != 1 {
+		t.Errorf("agents = %+v, evicted = %v, want calc-1 removed", r.Agents(), *evicted)
+	}
+}
@@ -0,0 +1,83 @@
+package agentpb
+
+import "github.com/superlogarifm/goCalc-v3/internal/models"
+
+// FromTask переводит задачу в сообщение, отправляемое агенту
+func FromTask(task models.Task) *Task {
+	return &Task{
+		Id:            task.ID,
+		Arg1:          task.Arg1,
+		Arg2:          task.Arg2,
+		Args:          task.Args,
+		Operation:     task.Operation,
+		OperationTime: task.OperationTime,
+		Mode:          string(task.Mode),
+		Scale:         int32(task.Scale),
+		ExpressionId:  task.ExpressionID,
+		LeaseId:       task.LeaseID,
+	}
+}
+
+// Model переводит полученную задачу в models.Task
+func (t *Task) Model() models.Task {
+	return models.Task{
+		ID:            t.GetId(),
+		Arg1:          t.GetArg1(),
+		Arg2:          t.GetArg2(),
+		Args:          t.GetArgs(),
+		Operation:     t.GetOperation(),
+		OperationTime: t.GetOperationTime(),
+		Mode:          models.CalculationMode(t.GetMode()),
+		Scale:         int(t.GetScale()),
+		ExpressionID:  t.GetExpressionId(),
+		LeaseID:       t.GetLeaseId(),
+	}
+}
+
+func FromTaskResult(result models.TaskResult) *TaskResult {
+	return &TaskResult{
+		Id:      result.ID,
+		Result:  result.Result,
+		Value:   result.Value,
+		LeaseId: result.LeaseID,
+		Error:   result.Error,
+	}
+}
+
+func (r *TaskResult) Model() models.TaskResult {
+	return models.TaskResult{
+		ID:      r.GetId(),
+		Result:  r.GetResult(),
+		Value:   r.GetValue(),
+		LeaseID: r.GetLeaseId(),
+		Error:   r.Error,
+	}
+}
+
+func FromRegistration(reg models.AgentRegistration) *Register {
+	modes := make([]string, 0, len(reg.Modes))
+	for _, mode := range reg.Modes {
+		modes = append(modes, string(mode))
+	}
+	return &Register{
+		Id:         reg.ID,
+		Hostname:   reg.Hostname,
+		Workers:    int32(reg.Workers),
+		Operations: reg.Operations,
+		Modes:      modes,
+	}
+}
+
+func (r *Register) Model() models.AgentRegistration {
+	var modes []models.CalculationMode
+	for _, mode := range r.GetModes() {
+		modes = append(modes, models.CalculationMode(mode))
+	}
+	return models.AgentRegistration{
+		ID:         r.GetId(),
+		Hostname:   r.GetHostname(),
+		Workers:    int(r.GetWorkers()),
+		Operations: r.GetOperations(),
+		Modes:      modes,
+	}
+}
@@ -0,0 +1,5 @@
+// Package agentpb содержит сообщения и gRPC-сервис протокола агентов,
+// сгенерированные из agent.proto.
+package agentpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative agent.proto
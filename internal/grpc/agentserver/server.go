@@ -0,0 +1,204 @@
+// Package agentserver обслуживает протокол агентов поверх gRPC. Задачи
+// выдаются из того же TaskManager, что и через /internal/task, поэтому
+// агенты обоих транспортов могут работать с одним оркестратором.
+package agentserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/superlogarifm/goCalc-v3/internal/auth"
+	"github.com/superlogarifm/goCalc-v3/internal/calculator"
+	"github.com/superlogarifm/goCalc-v3/internal/fleet"
+	"github.com/superlogarifm/goCalc-v3/internal/grpc/agentpb"
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type Server struct {
+	agentpb.UnimplementedAgentServiceServer
+
+	taskManager *calculator.TaskManager
+	fleet       *fleet.Registry
+	agentAuth   *auth.AgentAuth // nil, если AGENT_SECRET не задан и проверка выключена
+}
+
+func New(tm *calculator.TaskManager, registry *fleet.Registry, agentAuth *auth.AgentAuth) *Server {
+	return &Server{taskManager: tm, fleet: registry, agentAuth: agentAuth}
+}
+
+// NewGRPCServer создаёт gRPC-сервер с сервисом агентов
+func (s *Server) NewGRPCServer() *grpc.Server {
+	gs := grpc.NewServer()
+	agentpb.RegisterAgentServiceServer(gs, s)
+	return gs
+}
+
+// Connect ведёт сессию агента: регистрирует его, присылает задачи на свободные
+// вычислители и принимает результаты. Закрытие потока исключает агента,
+// и его невыполненные задачи сразу возвращаются в очередь.
+func (s *Server) Connect(stream agentpb.AgentService_ConnectServer) error {
+	if err := s.authenticate(stream.Context()); err != nil {
+		return err
+	}
+
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	reg := first.GetRegister()
+	if reg == nil {
+		return status.Error(codes.InvalidArgument, "first message must be register")
+	}
+	if reg.GetWorkers() < 0 {
+		return status.Error(codes.InvalidArgument, "workers must not be negative")
+	}
+
+	agent := s.fleet.Register(reg.Model())
+	defer s.fleet.Disconnect(agent.ID, agent.RegisteredAt)
+
+	err = stream.Send(&agentpb.OrchestratorMessage{Payload: &agentpb.OrchestratorMessage_Registered{
+		Registered: &agentpb.Registered{
+			Id:                  agent.ID,
+			HeartbeatIntervalMs: s.fleet.HeartbeatInterval().Milliseconds(),
+		},
+	}})
+	if err != nil {
+		return err
+	}
+
+	modes := agent.Modes
+	if len(modes) == 0 {
+		modes = []models.CalculationMode{models.ModeFloat}
+	}
+	return s.serve(stream, agent.ID, modes)
+}
+
+// serve обслуживает сессию зарегистрированного агента. Перед возвратом она
+// дожидается незавершённого ожидания задачи, чтобы аренда не была выдана
+// агенту уже после того, как Connect вернул его задачи в очередь.
+func (s *Server) serve(stream agentpb.AgentService_ConnectServer, agentID string, modes []models.CalculationMode) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	messages := make(chan *agentpb.AgentMessage)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			select {
+			case messages <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	slots := 0
+	// fetched получает задачу из ожидания в TaskManager; nil, пока ожидания нет
+	var fetched chan *models.Task
+	defer func() {
+		cancel()
+		if fetched == nil {
+			return
+		}
+		// задачу, выданную ожиданием в последний момент, агент уже не получит
+		if task := <-fetched; task != nil {
+			s.taskManager.ReleaseAgentTasks(agentID)
+		}
+	}()
+	for {
+		if slots > 0 && fetched == nil {
+			fetched = make(chan *models.Task, 1)
+			go func(out chan<- *models.Task) {
+				task, _ := s.taskManager.WaitNextTaskForAgent(ctx, agentID, modes)
+				out <- task
+			}(fetched)
+		}
+
+		select {
+		case task := <-fetched:
+			fetched = nil
+			if task == nil {
+				return ctx.Err()
+			}
+			if !s.fleet.Registered(agentID) {
+				// агента исключили, пока он ждал задачу
+				s.taskManager.ReleaseAgentTasks(agentID)
+				return status.Error(codes.Unauthenticated, "agent "+agentID+" is not registered")
+			}
+			err := stream.Send(&agentpb.OrchestratorMessage{Payload: &agentpb.OrchestratorMessage_Task{Task: agentpb.FromTask(*task)}})
+			if err != nil {
+				return err
+			}
+			slots--
+		case msg := <-messages:
+			// любое сообщение подтверждает, что агент на связи
+			if err := s.fleet.Heartbeat(agentID); err != nil {
+				return status.Error(codes.Unauthenticated, err.Error())
+			}
+			switch payload := msg.GetPayload().(type) {
+			case *agentpb.AgentMessage_Ready:
+				slots += int(payload.Ready.GetSlots())
+			case *agentpb.AgentMessage_Result:
+				if err := s.acceptResult(stream, agentID, payload.Result); err != nil {
+					return err
+				}
+			case *agentpb.AgentMessage_Register:
+				return status.Error(codes.InvalidArgument, "agent is already registered on this stream")
+			}
+		case err := <-recvErr:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// acceptResult передаёт результат в TaskManager и подтверждает приём;
+// отклонённый результат (чужая или истёкшая аренда) не обрывает сессию
+func (s *Server) acceptResult(stream agentpb.AgentService_ConnectServer, agentID string, result *agentpb.TaskResult) error {
+	ack := &agentpb.ResultAck{TaskId: result.GetId()}
+	if err := s.taskManager.UpdateTaskResultForAgent(agentID, result.Model()); err != nil {
+		ack.Error = err.Error()
+	}
+	return stream.Send(&agentpb.OrchestratorMessage{Payload: &agentpb.OrchestratorMessage_Ack{Ack: ack}})
+}
+
+// authenticate требует общий секрет агентов в метаданных authorization
+func (s *Server) authenticate(ctx context.Context) error {
+	if s.agentAuth == nil {
+		return nil
+	}
+	var secret string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			scheme, value, found := strings.Cut(values[0], " ")
+			if found && strings.EqualFold(scheme, "bearer") {
+				secret = value
+			}
+		}
+	}
+	if secret == "" || !s.agentAuth.CheckSecret(secret) {
+		addr := "unknown"
+		if p, ok := peer.FromContext(ctx); ok {
+			addr = p.Addr.String()
+		}
+		log.Printf("[AgentAuth] Rejected gRPC Connect from %s: agent secret required", addr)
+		return status.Error(codes.Unauthenticated, "agent secret required")
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+package agentserver
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/superlogarifm/goCalc-v3/internal/auth"
+	"github.com/superlogarifm/goCalc-v3/internal/calculator"
+	"github.com/superlogarifm/goCalc-v3/internal/fleet"
+	"github.com/superlogarifm/goCalc-v3/internal/grpc/agentpb"
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// startServer запускает сервис агентов на локальном порту и возвращает клиента
+func startServer(t *testing.T, agentAuth *auth.AgentAuth) (*calculator.TaskManager, *fleet.Registry, agentpb.AgentServiceClient) {
+	t.Helper()
+	tm := calculator.NewTaskManager()
+	registry := fleet.NewRegistry(time.Minute, func(agentID string) { tm.ReleaseAgentTasks(agentID) })
+	s := New(tm, registry, agentAuth)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := s.NewGRPCServer()
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return tm, registry, agentpb.NewAgentServiceClient(conn)
+}
+
+// connect открывает поток и регистрирует агента
+func connect(t *testing.T, ctx context.Context, client agentpb.AgentServiceClient, reg models.AgentRegistration) (agentpb.AgentService_ConnectClient, *agentpb.Registered) {
+	t.Helper()
+	stream, err := client.Connect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Send(&agentpb.AgentMessage{Payload: &agentpb.AgentMessage_Register{Register: agentpb.FromRegistration(reg)}}); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := stream.Recv()
+	if err != nil || msg.GetRegistered() == nil {
+		t.Fatalf("Recv() = %v, %v, want registered", msg, err)
+	}
+	return stream, msg.GetRegistered()
+}
+
+func ready(slots int32) *agentpb.AgentMessage {
+	return &agentpb.AgentMessage{Payload: &agentpb.AgentMessage_Ready{Ready: &agentpb.Ready{Slots: slots}}}
+}
+
+// waitFor ждёт выполнения условия, которое сервер выполняет асинхронно
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestServer_Connect(t *testing.T) {
+	tm, registry, client := startServer(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, registered := connect(t, ctx, client, models.AgentRegistration{Hostname: "calc-1", Workers: 1})
+	if registered.GetId() == "" || registered.GetHeartbeatIntervalMs() != (20*time.Second).Milliseconds() {
+		t.Fatalf("Registered = %+v", registered)
+	}
+	if agents := registry.Agents(); len(agents) != 1 || agents[0].ID != registered.GetId() {
+		t.Fatalf("registry agents = %+v", agents)
+	}
+
+	// задача появляется после ready: сессия сама находит её в очереди
+	if err := stream.Send(ready(1)); err != nil {
+		t.Fatal(err)
+	}
+	id, err := tm.CreateExpression(models.CalculateRequest{Expression: "(2+3)*4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		msg, err := stream.Recv()
+		if err != nil || msg.GetTask() == nil {
+			t.Fatalf("Recv() = %v, %v, want task", msg, err)
+		}
+		task := msg.GetTask().Model()
+		if leases := tm.Leases(); len(leases) != 1 || leases[0].AgentID != registered.GetId() {
+			t.Errorf("Leases() = %+v, want lease held by %s", leases, registered.GetId())
+		}
+
+		result := calculator.EvaluateTask(task)
+		if err := stream.Send(&agentpb.AgentMessage{Payload: &agentpb.AgentMessage_Result{Result: agentpb.FromTaskResult(result)}}); err != nil {
+			t.Fatal(err)
+		}
+		msg, err = stream.Recv()
+		if err != nil || msg.GetAck().GetTaskId() != task.ID || msg.GetAck().GetError() != "" {
+			t.Fatalf("Recv() = %v, %v, want ack for %s", msg, err, task.ID)
+		}
+		if err := stream.Send(ready(1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expr, ok := tm.GetExpression(id)
+	if !ok || expr.Status != models.StatusCompleted || expr.Result == nil || *expr.Result != 20 {
+		t.Errorf("expression = %+v, want completed with 20", expr)
+	}
+
+	// повторный результат отклоняется, но сессия продолжается
+	dup := &agentpb.TaskResult{Id: "1", Result: 5}
+	if err := stream.Send(&agentpb.AgentMessage{Payload: &agentpb.AgentMessage_Result{Result: dup}}); err != nil {
+		t.Fatal(err)
+	}
+	if msg, err := stream.Recv(); err != nil || msg.GetAck().GetError() == "" {
+		t.Errorf("Recv() = %v, %v, want ack with error", msg, err)
+	}
+}
+
+func TestServer_DisconnectReleasesTasks(t *testing.T) {
+	tm, registry, client := startServer(t, nil)
+	if _, err := tm.CreateExpression(models.CalculateRequest{Expression: "2+3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, _ := connect(t, ctx, client, models.AgentRegistration{ID: "calc-1", Workers: 1})
+	if err := stream.Send(ready(1)); err != nil {
+		t.Fatal(err)
+	}
+	if msg, err := stream.Recv(); err != nil || msg.GetTask() == nil {
+		t.Fatalf("Recv() = %v, %v, want task", msg, err)
+	}
+	cancel()
+
+	waitFor(t, "agent removal", func() bool { return len(registry.Agents()) == 0 })
+	if leases := tm.Leases(); len(leases) != 0 {
+		t.Errorf("Leases() = %+v after disconnect, want none", leases)
+	}
+	if _, ok := tm.GetNextTask(); !ok {
+		t.Error("task was not returned to the queue")
+	}
+}
+
+func TestServer_RemovedAgent(t *testing.T) {
+	tm, registry, client := startServer(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, registered := connect(t, ctx, client, models.AgentRegistration{ID: "calc-1", Workers: 1})
+	if err := registry.Remove(registered.GetId()); err != nil {
+		t.Fatal(err)
+	}
+
+	// исключённый агент не получает задач, поток закрывается
+	if err := stream.Send(ready(1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tm.CreateExpression(models.CalculateRequest{Expression: "2+3"}); err != nil {
+		t.Fatal(err)
+	}
+	if msg, err := stream.Recv(); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("Recv() = %v, %v, want %v", msg, err, codes.Unauthenticated)
+	}
+	if leases := tm.Leases(); len(leases) != 0 {
+		t.Errorf("Leases() = %+v for removed agent, want none", leases)
+	}
+}
+
+// fakeStream отдаёт серверу заранее заданные сообщения, а затем io.EOF.
+// Его контекст не отменяется сам, как у потока gRPC до возврата из Connect.
+type fakeStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	messages chan *agentpb.AgentMessage
+}
+
+func (f *fakeStream) Context() context.Context { return f.ctx }
+
+func (f *fakeStream) Send(*agentpb.OrchestratorMessage) error { return nil }
+
+func (f *fakeStream) Recv() (*agentpb.AgentMessage, error) {
+	msg, ok := <-f.messages
+	if !ok {
+		return nil, io.EOF
+	}
+	return msg, nil
+}
+
+func TestServer_DisconnectDuringFetch(t *testing.T) {
+	tm := calculator.NewTaskManager()
+	registry := fleet.NewRegistry(time.Minute, func(agentID string) { tm.ReleaseAgentTasks(agentID) })
+	s := New(tm, registry, nil)
+	agent := registry.Register(models.AgentRegistration{ID: "calc-1", Workers: 1})
+
+	// агент сообщает о свободном вычислителе при пустой очереди и закрывает поток
+	stream := &fakeStream{ctx: context.Background(), messages: make(chan *agentpb.AgentMessage, 1)}
+	stream.messages <- ready(1)
+	close(stream.messages)
+	if err := s.serve(stream, agent.ID, []models.CalculationMode{models.ModeFloat}); err != nil {
+		t.Fatalf("serve() = %v, want nil", err)
+	}
+	registry.Disconnect(agent.ID, agent.RegisteredAt)
+
+	// задача, появившаяся после отключения, не достаётся ожиданию закрытой сессии
+	if _, err := tm.CreateExpression(models.CalculateRequest{Expression: "2+3"}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if leases := tm.Leases(); len(leases) != 0 {
+		t.Errorf("Leases() = %+v after disconnect, want none", leases)
+	}
+	if _, ok := tm.GetNextTask(); !ok {
+		t.Error("task is not in the queue")
+	}
+}
+
+func TestServer_Authentication(t *testing.T) {
+	agentAuth, err := auth.NewAgentAuth("fleet-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, registry, client := startServer(t, agentAuth)
+
+	tests := []struct {
+		name     string
+		header   string
+		wantCode codes.Code
+	}{
+		{"без секрета", "", codes.Unauthenticated},
+		{"неверный секрет", "Bearer wrong", codes.Unauthenticated},
+		{"без схемы Bearer", "fleet-secret", codes.Unauthenticated},
+		{"верный секрет", "Bearer fleet-secret", codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.header != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", tt.header)
+			}
+			stream, err := client.Connect(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stream.Send(&agentpb.AgentMessage{Payload: &agentpb.AgentMessage_Register{Register: &agentpb.Register{Hostname: "calc-1"}}})
+			_, err = stream.Recv()
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("Recv() code = %v, want %v", code, tt.wantCode)
+			}
+		})
+	}
+	waitFor(t, "agent removal", func() bool { return len(registry.Agents()) == 0 })
+}
+
+func TestServer_FirstMessageMustRegister(t *testing.T) {
+	_, _, client := startServer(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.Connect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Send(ready(1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Recv() error = %v, want InvalidArgument", err)
+	}
+}
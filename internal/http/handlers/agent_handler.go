@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/superlogarifm/goCalc-v3/internal/auth"
+	"github.com/superlogarifm/goCalc-v3/internal/calculator"
+	"github.com/superlogarifm/goCalc-v3/internal/fleet"
+	"github.com/superlogarifm/goCalc-v3/internal/http/middleware"
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+)
+
+// AgentHandler реализует протокол агентов: регистрацию и heartbeat, выдачу
+// задач, приём результатов и просмотр аренд. Используется оркестратором и calc_service.
+type AgentHandler struct {
+	taskManager *calculator.TaskManager
+	fleet       *fleet.Registry
+	agentAuth   *auth.AgentAuth // nil, если AGENT_SECRET не задан и проверка выключена
+	adminAuth   *auth.AdminAuth // nil, если ADMIN_SECRET не задан
+}
+
+func NewAgentHandler(tm *calculator.TaskManager, registry *fleet.Registry, agentAuth *auth.AgentAuth, adminAuth *auth.AdminAuth) *AgentHandler {
+	return &AgentHandler{taskManager: tm, fleet: registry, agentAuth: agentAuth, adminAuth: adminAuth}
+}
+
+// agentID возвращает ID зарегистрированного агента и отмечает его активность.
+// При включённой проверке ID берётся из токена, иначе из заголовка X-Agent-ID.
+// Без проверки незнакомый агент (например, исключённый) обслуживается как
+// анонимный, пока не зарегистрируется заново; с проверкой его отклоняет Authenticate.
+func (h *AgentHandler) agentID(r *http.Request) string {
+	id, ok := middleware.GetAgentIDFromContext(r.Context())
+	if !ok {
+		id = r.Header.Get(models.AgentIDHeader)
+	}
+	if id == "" {
+		return ""
+	}
+	if err := h.fleet.Heartbeat(id); err != nil {
+		return ""
+	}
+	return id
+}
+
+// tokenAgentID возвращает ID агента из проверенного токена: только его
+// результаты сверяются с держателем аренды. Без проверки ID пустой.
+func tokenAgentID(r *http.Request) string {
+	id, _ := middleware.GetAgentIDFromContext(r.Context())
+	return id
+}
+
+// HandleTask обслуживает /internal/task: GET выдаёт задачу, POST принимает результат
+func (h *AgentHandler) HandleTask(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.HandleGetTask(w, r)
+	case http.MethodPost:
+		h.HandleTaskResult(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *AgentHandler) HandleGetTask(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	wait, err := taskWait(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid wait: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	agentID, modes := h.agentID(r), agentModes(r)
+	var task *models.Task
+	var ok bool
+	if wait > 0 {
+		// long polling: ответ уходит, как только задача готова, или по истечении wait
+		ctx, cancel := context.WithTimeout(r.Context(), wait)
+		defer cancel()
+		task, ok = h.taskManager.WaitNextTaskForAgent(ctx, agentID, modes)
+	} else {
+		task, ok = h.taskManager.GetNextTaskForAgent(agentID, modes)
+	}
+	if ok {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.TaskResponse{Task: *task})
+		return
+	}
+
+	http.Error(w, "No tasks available", http.StatusNotFound)
+}
+
+// MaxTaskWait ограничивает ожидание задачи в GET /internal/task?wait=
+const MaxTaskWait = 60 * time.Second
+
+// MaxTaskBatch ограничивает число задач и результатов в одном запросе
+const MaxTaskBatch = 100
+
+// HandleGetTasks выдаёт пачку до N готовых задач: GET /internal/tasks?max=N.
+// Параметры modes и wait работают как в /internal/task; с wait ответ уходит,
+// как только готова хотя бы одна задача.
+func (h *AgentHandler) HandleGetTasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, err := taskBatchSize(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid max: %v", err), http.StatusBadRequest)
+		return
+	}
+	wait, err := taskWait(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid wait: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	agentID, modes := h.agentID(r), agentModes(r)
+	var tasks []models.Task
+	if wait > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), wait)
+		defer cancel()
+		tasks = h.taskManager.WaitNextTasksForAgent(ctx, agentID, modes, limit)
+	} else {
+		tasks = h.taskManager.GetNextTasksForAgent(agentID, modes, limit)
+	}
+	if len(tasks) == 0 {
+		http.Error(w, "No tasks available", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, models.TasksResponse{Tasks: tasks})
+}
+
+// taskBatchSize возвращает размер пачки из параметра max: по умолчанию 1,
+// больше MaxTaskBatch не выдаётся
+func taskBatchSize(r *http.Request) (int, error) {
+	param := r.URL.Query().Get("max")
+	if param == "" {
+		return 1, nil
+	}
+	limit, err := strconv.Atoi(param)
+	if err != nil {
+		return 0, err
+	}
+	if limit <= 0 {
+		return 0, errors.New("must be positive")
+	}
+	if limit > MaxTaskBatch {
+		limit = MaxTaskBatch
+	}
+	return limit, nil
+}
+
+// taskWait возвращает время ожидания задачи из параметра wait (например, 30s);
+// без параметра задача выдаётся без ожидания
+func taskWait(r *http.Request) (time.Duration, error) {
+	param := r.URL.Query().Get("wait")
+	if param == "" {
+		return 0, nil
+	}
+	wait, err := time.ParseDuration(param)
+	if err != nil {
+		return 0, err
+	}
+	if wait < 0 {
+		return 0, errors.New("must not be negative")
+	}
+	if wait > MaxTaskWait {
+		wait = MaxTaskWait
+	}
+	return wait, nil
+}
+
+// agentModes возвращает режимы вычислений, заявленные агентом в параметре modes.
+// Агенты, которые не передают параметр, умеют считать только в float64.
+func agentModes(r *http.Request) []models.CalculationMode {
+	param := r.URL.Query().Get("modes")
+	if param == "" {
+		return []models.CalculationMode{models.ModeFloat}
+	}
+
+	var modes []models.CalculationMode
+	for _, mode := range strings.Split(param, ",") {
+		modes = append(modes, models.CalculationMode(strings.TrimSpace(mode)))
+	}
+	return modes
+}
+
+func (h *AgentHandler) HandleTaskResult(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var result models.TaskResult
+	if err := json.NewDecoder(r.Body).Decode(&result); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	h.agentID(r)
+	if err := h.taskManager.UpdateTaskResultForAgent(tokenAgentID(r), result); err != nil {
+		switch {
+		case errors.Is(err, calculator.ErrTaskNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, calculator.ErrTaskCompleted),
+			errors.Is(err, calculator.ErrLeaseExpired),
+			errors.Is(err, calculator.ErrLeaseMismatch),
+			errors.Is(err, calculator.ErrLeaseRequired):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// HandleTaskResults принимает массив результатов: POST /internal/results.
+// Результаты применяются по одному, отклонённый (например, по истёкшей аренде)
+// не мешает остальным; итог по каждому возвращается в том же порядке.
+func (h *AgentHandler) HandleTaskResults(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var results []models.TaskResult
+	if err := json.NewDecoder(r.Body).Decode(&results); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+	if len(results) > MaxTaskBatch {
+		http.Error(w, fmt.Sprintf("Invalid request: more than %d results", MaxTaskBatch), http.StatusUnprocessableEntity)
+		return
+	}
+
+	h.agentID(r)
+	owner := tokenAgentID(r)
+	statuses := make([]models.TaskResultStatus, 0, len(results))
+	for _, result := range results {
+		status := models.TaskResultStatus{ID: result.ID, Status: "success"}
+		if err := h.taskManager.UpdateTaskResultForAgent(owner, result); err != nil {
+			status.Status = "error"
+			status.Error = err.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	writeJSON(w, http.StatusOK, models.TaskResultsResponse{Results: statuses})
+}
+
+// HandleGetLeases показывает задачи, выданные агентам и ещё не вернувшиеся
+func (h *AgentHandler) HandleGetLeases(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.LeasesResponse{Leases: h.taskManager.Leases()})
+}
+
+// HandleAgents обслуживает /internal/agents: POST регистрирует агента, GET показывает парк
+func (h *AgentHandler) HandleAgents(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.handleRegisterAgent(w, r)
+	case http.MethodGet:
+		h.handleListAgents(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *AgentHandler) handleRegisterAgent(w http.ResponseWriter, r *http.Request) {
+	var reg models.AgentRegistration
+	if err := json.NewDecoder(r.Body).Decode(&reg); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+	if reg.Workers < 0 {
+		http.Error(w, "Invalid request: workers must not be negative", http.StatusUnprocessableEntity)
+		return
+	}
+
+	agent := h.fleet.Register(reg)
+	resp := models.AgentRegistrationResponse{
+		ID:                  agent.ID,
+		HeartbeatIntervalMs: h.fleet.HeartbeatInterval().Milliseconds(),
+	}
+	if h.agentAuth != nil {
+		resp.Token = h.agentAuth.IssueToken(agent.ID)
+	}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// handleListAgents показывает живых агентов вместе с выданными им задачами
+func (h *AgentHandler) handleListAgents(w http.ResponseWriter, r *http.Request) {
+	agents := h.fleet.Agents()
+	inFlight := make(map[string][]models.TaskLease)
+	for _, lease := range h.taskManager.Leases() {
+		if lease.AgentID != "" {
+			inFlight[lease.AgentID] = append(inFlight[lease.AgentID], lease)
+		}
+	}
+	for i := range agents {
+		agents[i].InFlight = inFlight[agents[i].ID]
+		if agents[i].InFlight == nil {
+			agents[i].InFlight = []models.TaskLease{}
+		}
+	}
+	writeJSON(w, http.StatusOK, models.AgentsResponse{Agents: agents})
+}
+
+// isHeartbeatPath сообщает, что путь — /internal/agents/{id}/heartbeat, и возвращает ID
+func isHeartbeatPath(path string) (string, bool) {
+	return strings.CutSuffix(strings.TrimPrefix(path, "/internal/agents/"), "/heartbeat")
+}
+
+// HandleHeartbeat обслуживает POST /internal/agents/{id}/heartbeat
+func (h *AgentHandler) HandleHeartbeat(w http.ResponseWriter, r *http.Request) {
+	id, ok := isHeartbeatPath(r.URL.Path)
+	if !ok {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// агент подтверждает только собственную активность
+	if tokenID, ok := middleware.GetAgentIDFromContext(r.Context()); ok && tokenID != id {
+		http.Error(w, "Forbidden: token belongs to another agent", http.StatusForbidden)
+		return
+	}
+	if err := h.fleet.Heartbeat(id); err != nil {
+		// агент заново регистрируется, получив 404
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleRemoveAgent обслуживает DELETE /internal/agents/{id} — исключение агента администратором
+func (h *AgentHandler) HandleRemoveAgent(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/internal/agents/")
+	if r.Method != http.MethodDelete || id == "" || strings.Contains(id, "/") {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	if err := h.fleet.Remove(id); err != nil {
+		if errors.Is(err, fleet.ErrAgentNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegisterRoutes подключает эндпоинты агентов к mux. Задачи и heartbeat
+// требуют токен агента, регистрация — общий секрет агентов, а просмотр
+// и управление парком — административный секрет.
+func (h *AgentHandler) RegisterRoutes(mux *http.ServeMux) {
+	m := middleware.NewAgentAuthMiddleware(h.agentAuth, h.adminAuth, h.fleet)
+	heartbeat := m.Authenticate(http.HandlerFunc(h.HandleHeartbeat))
+	removeAgent := m.RequireAdmin(http.HandlerFunc(h.HandleRemoveAgent))
+	registerAgent := m.RequireSecret(http.HandlerFunc(h.HandleAgents))
+	listAgents := m.RequireAdmin(http.HandlerFunc(h.HandleAgents))
+
+	mux.Handle("/internal/task", m.Authenticate(http.HandlerFunc(h.HandleTask)))
+	mux.Handle("/internal/tasks", m.Authenticate(http.HandlerFunc(h.HandleGetTasks)))
+	mux.Handle("/internal/results", m.Authenticate(http.HandlerFunc(h.HandleTaskResults)))
+	mux.Handle("/internal/leases", m.RequireAdmin(http.HandlerFunc(h.HandleGetLeases)))
+	mux.HandleFunc("/internal/agents", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			registerAgent.ServeHTTP(w, r)
+			return
+		}
+		listAgents.ServeHTTP(w, r)
+	})
+	mux.HandleFunc("/internal/agents/", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := isHeartbeatPath(r.URL.Path); ok {
+			heartbeat.ServeHTTP(w, r)
+			return
+		}
+		removeAgent.ServeHTTP(w, r)
+	})
+}
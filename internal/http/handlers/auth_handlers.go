@@ -40,6 +40,7 @@ func (h *AuthHandlers) Register(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
+	req.Login = models.NormalizeLogin(req.Login)
 	if req.Login == "" || req.Password == "" {
 		http.Error(w, "Login and password are required", http.StatusBadRequest)
 		return
@@ -97,6 +98,7 @@ func (h *AuthHandlers) Login(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
+	req.Login = models.NormalizeLogin(req.Login)
 	if req.Login == "" || req.Password == "" {
 		http.Error(w, "Login and password are required", http.StatusBadRequest)
 		return
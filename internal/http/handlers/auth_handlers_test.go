@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/superlogarifm/goCalc-v3/internal/auth"
+	"github.com/superlogarifm/goCalc-v3/internal/storage/memory"
+)
+
+func newTestAuthHandlers(t *testing.T) *AuthHandlers {
+	t.Helper()
+	authService, err := auth.NewAuthService("test-secret", time.Hour)
+	if err != nil {
+		t.Fatalf("NewAuthService() error = %v", err)
+	}
+	return NewAuthHandlers(authService, memory.NewUserRepository())
+}
+
+func TestAuthHandlers(t *testing.T) {
+	h := newTestAuthHandlers(t)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+		want    int
+	}{
+		{"регистрация", h.Register, `{"login":"alice","password":"secret1"}`, http.StatusOK},
+		{"повторная регистрация", h.Register, `{"login":"alice","password":"secret2"}`, http.StatusConflict},
+		{"логин в другом регистре", h.Register, `{"login":" ALICE ","password":"secret2"}`, http.StatusConflict},
+		{"пустой логин", h.Register, `{"login":"   ","password":"secret1"}`, http.StatusBadRequest},
+		{"короткий пароль", h.Register, `{"login":"bob","password":"123"}`, http.StatusBadRequest},
+		{"вход", h.Login, `{"login":"alice","password":"secret1"}`, http.StatusOK},
+		{"вход в другом регистре", h.Login, `{"login":"Alice","password":"secret1"}`, http.StatusOK},
+		{"неверный пароль", h.Login, `{"login":"alice","password":"secret2"}`, http.StatusUnauthorized},
+		{"неизвестный пользователь", h.Login, `{"login":"bob","password":"secret1"}`, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			tt.handler(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d; body: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthHandlers_LoginToken(t *testing.T) {
+	h := newTestAuthHandlers(t)
+	body := `{"login":"alice","password":"secret1"}`
+	h.Register(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/register", strings.NewReader(body)))
+
+	w := httptest.NewRecorder()
+	h.Login(w, httptest.NewRequest(http.MethodPost, "/api/v1/login", strings.NewReader(body)))
+
+	var resp LoginResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	userID, login, err := h.AuthService.ValidateToken(resp.Token)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if userID == 0 || login != "alice" {
+		t.Errorf("token = (%d, %q), want alice with a non-zero ID", userID, login)
+	}
+}
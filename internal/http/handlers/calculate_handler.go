@@ -3,6 +3,7 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net/http"
@@ -13,10 +14,6 @@ import (
 	"github.com/superlogarifm/goCalc-v3/internal/models"
 )
 
-type CalculateRequest struct {
-	Expression string `json:"expression"`
-}
-
 type CalculateResponse struct {
 	ExpressionID string `json:"expression_id"`
 }
@@ -52,7 +49,7 @@ func (h *CalculateHandler) HandleCalculate(w http.ResponseWriter, r *http.Reques
 	r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 	log.Printf("Raw request body: %s\n", string(bodyBytes))
 
-	var req CalculateRequest
+	var req models.CalculateRequest
 	w.Header().Set("Content-Type", "application/json")
 
 	decoder := json.NewDecoder(r.Body)
@@ -68,9 +65,15 @@ func (h *CalculateHandler) HandleCalculate(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	expressionID, err := h.taskManager.CreateExpression(req.Expression)
+	req.OwnerID = userID
+	expressionID, err := h.taskManager.CreateExpression(req)
+	if errors.Is(err, calculator.ErrStorage) {
+		log.Printf("Error creating expression for UserID %d: %v\n", userID, err)
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: "Internal Server Error"})
+		return
+	}
 	if err != nil {
-		http.Error(w, `{"error": "invalid expression"}`, http.StatusUnprocessableEntity)
+		writeJSON(w, http.StatusUnprocessableEntity, calculator.NewErrorResponse(err))
 		return
 	}
 
@@ -93,7 +96,7 @@ func (h *CalculateHandler) HandleGetExpressions(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	expressions := h.taskManager.GetAllExpressions()
+	expressions := h.taskManager.GetUserExpressions(userID)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(models.ExpressionsResponse{Expressions: expressions})
 }
@@ -118,7 +121,8 @@ func (h *CalculateHandler) HandleGetExpressionByID(w http.ResponseWriter, r *htt
 	}
 	log.Printf("Received get expression by ID request from UserID: %d for ExpressionID: %s\n", userID, id)
 
-	expression, found := h.taskManager.GetExpression(id)
+	// чужое выражение неотличимо от несуществующего
+	expression, found := h.taskManager.GetUserExpression(id, userID)
 	if !found {
 		http.Error(w, `{"error": "Expression not found"}`, http.StatusNotFound)
 		return
@@ -127,3 +131,56 @@ func (h *CalculateHandler) HandleGetExpressionByID(w http.ResponseWriter, r *htt
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(models.ExpressionResponse{Expression: *expression})
 }
+
+// HandleExpressionByID разбирает маршруты /api/v1/expressions/{id}:
+// GET возвращает выражение, DELETE и POST .../cancel отменяют его
+func (h *CalculateHandler) HandleExpressionByID(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/expressions/")
+	if id, ok := strings.CutSuffix(path, "/cancel"); ok {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.handleCancelExpression(w, r, id)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		h.handleCancelExpression(w, r, path)
+		return
+	}
+	h.HandleGetExpressionByID(w, r)
+}
+
+func (h *CalculateHandler) handleCancelExpression(w http.ResponseWriter, r *http.Request, id string) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		log.Println("Error: User ID not found in context for CancelExpression")
+		http.Error(w, "Internal Server Error: User context missing", http.StatusInternalServerError)
+		return
+	}
+
+	if id == "" {
+		http.Error(w, `{"error": "Expression ID is required in the path"}`, http.StatusBadRequest)
+		return
+	}
+	log.Printf("Received cancel expression request from UserID: %d for ExpressionID: %s\n", userID, id)
+
+	expression, err := h.taskManager.CancelExpression(id, userID)
+	switch {
+	case errors.Is(err, calculator.ErrExpressionNotFound):
+		writeJSON(w, http.StatusNotFound, models.ErrorResponse{Error: "Expression not found"})
+	case errors.Is(err, calculator.ErrExpressionFinished):
+		writeJSON(w, http.StatusConflict, models.ErrorResponse{Error: "Expression already finished"})
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, models.ErrorResponse{Error: err.Error()})
+	default:
+		writeJSON(w, http.StatusOK, models.ExpressionResponse{Expression: *expression})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/superlogarifm/goCalc-v3/internal/calculator"
+	"github.com/superlogarifm/goCalc-v3/internal/http/middleware"
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+	"github.com/superlogarifm/goCalc-v3/internal/storage/memory"
+)
+
+// brokenExpressionRepository имитирует недоступную базу при сохранении выражения
+type brokenExpressionRepository struct {
+	*memory.ExpressionRepository
+}
+
+func (brokenExpressionRepository) SaveExpression(ctx context.Context, expr *models.Expression) error {
+	return errors.New("connection refused")
+}
+
+func TestHandleCalculate_Status(t *testing.T) {
+	broken, err := calculator.NewTaskManagerWithStorage(
+		brokenExpressionRepository{memory.NewExpressionRepository()}, memory.NewTaskRepository())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		tm   *calculator.TaskManager
+		body string
+		want int
+	}{
+		{"выражение принято", calculator.NewTaskManager(), `{"expression": "2+2"}`, http.StatusCreated},
+		{"ошибка разбора", calculator.NewTaskManager(), `{"expression": "2+"}`, http.StatusUnprocessableEntity},
+		{"сбой хранилища", broken, `{"expression": "2+2"}`, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/calculate", strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, uint(1)))
+			w := httptest.NewRecorder()
+			NewCalculateHandler(tt.tm).HandleCalculate(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d; body: %s", w.Code, tt.want, w.Body.String())
+			}
+			if strings.Contains(w.Body.String(), "connection refused") {
+				t.Errorf("body = %s, storage error leaked to the client", w.Body.String())
+			}
+		})
+	}
+}
+
+// requestAs возвращает запрос пользователя, прошедшего AuthMiddleware
+func requestAs(userID uint, method, target string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	return req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+}
+
+func TestHandleExpressionByID_Cancel(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		suffix string
+		user   uint
+		expr   string
+		want   int
+	}{
+		{"DELETE", http.MethodDelete, "", 1, "2+2", http.StatusOK},
+		{"POST cancel", http.MethodPost, "/cancel", 1, "2+2", http.StatusOK},
+		{"чужое выражение", http.MethodDelete, "", 2, "2+2", http.StatusNotFound},
+		{"чужое выражение через cancel", http.MethodPost, "/cancel", 2, "2+2", http.StatusNotFound},
+		{"выражение завершено", http.MethodDelete, "", 1, "42", http.StatusConflict},
+		{"завершено, cancel", http.MethodPost, "/cancel", 1, "42", http.StatusConflict},
+		{"GET cancel", http.MethodGet, "/cancel", 1, "2+2", http.StatusMethodNotAllowed},
+		{"PUT", http.MethodPut, "", 1, "2+2", http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm := calculator.NewTaskManager()
+			id, err := tm.CreateExpression(models.CalculateRequest{Expression: tt.expr, OwnerID: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			NewCalculateHandler(tm).HandleExpressionByID(w, requestAs(tt.user, tt.method, "/api/v1/expressions/"+id+tt.suffix))
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d; body: %s", w.Code, tt.want, w.Body.String())
+			}
+
+			expr, _ := tm.GetExpression(id)
+			cancelled := expr.Status == models.StatusCancelled
+			if cancelled != (tt.want == http.StatusOK) {
+				t.Errorf("expression status = %s after %s %s", expr.Status, tt.method, tt.suffix)
+			}
+			if tt.want == http.StatusOK {
+				var resp models.ExpressionResponse
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || resp.Expression.Status != models.StatusCancelled {
+					t.Errorf("response = %+v, %v, want cancelled expression", resp, err)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleExpressionByID_Owner(t *testing.T) {
+	tm := calculator.NewTaskManager()
+	id, err := tm.CreateExpression(models.CalculateRequest{Expression: "2+2", OwnerID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		user uint
+		id   string
+		want int
+	}{
+		{"своё выражение", 1, id, http.StatusOK},
+		{"чужое выражение", 2, id, http.StatusNotFound},
+		{"несуществующее выражение", 1, "404", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			NewCalculateHandler(tm).HandleExpressionByID(w, requestAs(tt.user, http.MethodGet, "/api/v1/expressions/"+tt.id))
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d; body: %s", w.Code, tt.want, w.Body.String())
+			}
+			if tt.want != http.StatusOK {
+				if strings.Contains(w.Body.String(), "2+2") {
+					t.Errorf("body = %s, expression of another user leaked", w.Body.String())
+				}
+				return
+			}
+			var resp models.ExpressionResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || resp.Expression.ID != id {
+				t.Errorf("response = %+v, %v, want expression %s", resp, err, id)
+			}
+		})
+	}
+}
+
+func TestHandleGetExpressions_Owner(t *testing.T) {
+	tm := calculator.NewTaskManager()
+	owned := map[uint][]string{}
+	for _, req := range []models.CalculateRequest{
+		{Expression: "1+1", OwnerID: 1},
+		{Expression: "2+2", OwnerID: 2},
+		{Expression: "3+3", OwnerID: 1},
+	} {
+		id, err := tm.CreateExpression(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		owned[req.OwnerID] = append(owned[req.OwnerID], id)
+	}
+
+	tests := []struct {
+		name string
+		user uint
+		want []string
+	}{
+		{"выражения первого пользователя", 1, owned[1]},
+		{"выражения второго пользователя", 2, owned[2]},
+		{"пользователь без выражений", 3, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			NewCalculateHandler(tm).HandleGetExpressions(w, requestAs(tt.user, http.MethodGet, "/api/v1/expressions"))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d; body: %s", w.Code, http.StatusOK, w.Body.String())
+			}
+			var resp models.ExpressionsResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]bool{}
+			for _, expr := range resp.Expressions {
+				got[expr.ID] = true
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("got expressions %v, want only %v", got, tt.want)
+			}
+			for _, id := range tt.want {
+				if !got[id] {
+					t.Errorf("expression %s is missing", id)
+				}
+			}
+		})
+	}
+}
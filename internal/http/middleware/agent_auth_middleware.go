@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/superlogarifm/goCalc-v3/internal/auth"
+)
+
+const AgentIDKey contextKey = "agentID"
+
+// AgentRegistry сообщает, состоит ли агент в парке
+type AgentRegistry interface {
+	Registered(agentID string) bool
+}
+
+// AgentAuthMiddleware защищает эндпоинты агентов. Без AgentAuth (AGENT_SECRET
+// не задан) запросы агентов пропускаются без проверки.
+type AgentAuthMiddleware struct {
+	AgentAuth *auth.AgentAuth
+	AdminAuth *auth.AdminAuth // nil, если ADMIN_SECRET не задан
+	Agents    AgentRegistry
+}
+
+func NewAgentAuthMiddleware(agentAuth *auth.AgentAuth, adminAuth *auth.AdminAuth, agents AgentRegistry) *AgentAuthMiddleware {
+	return &AgentAuthMiddleware{AgentAuth: agentAuth, AdminAuth: adminAuth, Agents: agents}
+}
+
+// Authenticate пропускает запросы с токеном агента из парка и кладёт ID агента
+// в контекст. Токен не хранится на сервере, поэтому токен исключённого агента
+// отзывается тем, что агента больше нет в парке.
+func (m *AgentAuthMiddleware) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.AgentAuth == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, ok := bearerToken(r)
+		if !ok {
+			rejectAgent(w, r, "agent token required")
+			return
+		}
+		agentID, err := m.AgentAuth.ValidateToken(token)
+		if err != nil {
+			rejectAgent(w, r, "invalid agent token")
+			return
+		}
+		if !m.Agents.Registered(agentID) {
+			rejectAgent(w, r, "agent "+agentID+" is not registered")
+			return
+		}
+		ctx := context.WithValue(r.Context(), AgentIDKey, agentID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireSecret пропускает только запросы с общим секретом агентов (регистрацию)
+func (m *AgentAuthMiddleware) RequireSecret(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.AgentAuth == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		secret, ok := bearerToken(r)
+		if !ok || !m.AgentAuth.CheckSecret(secret) {
+			rejectAgent(w, r, "agent secret required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireAdmin пропускает только запросы с секретом ADMIN_SECRET. Если он
+// не задан, административные запросы открыты, только пока выключена и проверка
+// агентов; иначе они отклоняются.
+func (m *AgentAuthMiddleware) RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.AdminAuth == nil {
+			if m.AgentAuth == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			rejectAgent(w, r, "admin endpoints are disabled, set ADMIN_SECRET")
+			return
+		}
+		secret, ok := bearerToken(r)
+		if !ok || !m.AdminAuth.CheckSecret(secret) {
+			rejectAgent(w, r, "admin secret required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	parts := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" || parts[1] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// rejectAgent отвечает 401 и записывает попытку в журнал; сам токен не логируется
+func rejectAgent(w http.ResponseWriter, r *http.Request, reason string) {
+	log.Printf("[AgentAuth] Rejected %s %s from %s: %s", r.Method, r.URL.Path, r.RemoteAddr, reason)
+	http.Error(w, "Unauthorized: "+reason, http.StatusUnauthorized)
+}
+
+// GetAgentIDFromContext возвращает ID агента, проверенный Authenticate
+func GetAgentIDFromContext(ctx context.Context) (string, bool) {
+	agentID, ok := ctx.Value(AgentIDKey).(string)
+	return agentID, ok
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// заголовок, которым зарегистрированный агент представляется при запросах задач и результатов
+const AgentIDHeader = "X-Agent-ID"
+
+// регистрация агента при запуске
+type AgentRegistration struct {
+	ID         string            `json:"id,omitempty"` // пустой — ID назначит оркестратор
+	Hostname   string            `json:"hostname"`
+	Workers    int               `json:"workers"`              // число параллельных вычислителей (COMPUTING_POWER)
+	Operations []string          `json:"operations,omitempty"` // операции, которые умеет вычислять агент
+	Modes      []CalculationMode `json:"modes,omitempty"`
+}
+
+// ответ на регистрацию: агент присылает heartbeat не реже HeartbeatIntervalMs
+type AgentRegistrationResponse struct {
+	ID                  string `json:"id"`
+	HeartbeatIntervalMs int64  `json:"heartbeat_interval_ms"`
+	Token               string `json:"token,omitempty"` // токен агента, если включена проверка AGENT_SECRET
+}
+
+// агент в парке оркестратора
+type AgentInfo struct {
+	ID           string            `json:"id"`
+	Hostname     string            `json:"hostname"`
+	Workers      int               `json:"workers"`
+	Operations   []string          `json:"operations,omitempty"`
+	Modes        []CalculationMode `json:"modes,omitempty"`
+	RegisteredAt time.Time         `json:"registered_at"`
+	LastSeen     time.Time         `json:"last_seen"`
+	InFlight     []TaskLease       `json:"in_flight"` // задачи, выданные агенту и ещё не вернувшиеся
+}
+
+// ответ со списком живых агентов
+type AgentsResponse struct {
+	Agents []AgentInfo `json:"agents"`
+}
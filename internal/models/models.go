@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // статус вычисления выражения
 type ExpressionStatus string
 
@@ -8,39 +10,89 @@ const (
 	StatusProcessing ExpressionStatus = "processing"
 	StatusCompleted  ExpressionStatus = "completed"
 	StatusError      ExpressionStatus = "error"
+	StatusCancelled  ExpressionStatus = "cancelled" // отменено владельцем, результаты задач отбрасываются
+)
+
+// режим вычисления выражения
+type CalculationMode string
+
+const (
+	ModeFloat    CalculationMode = "float"
+	ModeDecimal  CalculationMode = "decimal"  // точная десятичная арифметика, значения передаются строками
+	ModeRational CalculationMode = "rational" // обыкновенные дроби, значения передаются строками вида "a/b"
 )
 
 // арифметическое выражение
 type Expression struct {
-	ID       string           `json:"id"`
-	Input    string           `json:"expression,omitempty"`
-	Status   ExpressionStatus `json:"status"`
-	Result   *float64         `json:"result,omitempty"`
-	ErrorMsg string           `json:"error,omitempty"`
+	ID        string             `json:"id"`
+	Input     string             `json:"expression,omitempty"`
+	Status    ExpressionStatus   `json:"status"`
+	Result    *float64           `json:"result,omitempty"` // в точных режимах пусто, если значение вне диапазона float64
+	Value     string             `json:"value,omitempty"`  // точный результат в десятичном и рациональном режимах
+	Fraction  *Fraction          `json:"fraction,omitempty"`
+	ErrorMsg  string             `json:"error,omitempty"`
+	Variables map[string]float64 `json:"variables,omitempty"`
+	Mode      CalculationMode    `json:"mode,omitempty"`
+	Scale     int                `json:"scale,omitempty"`
+	OwnerID   uint               `json:"-"` // пользователь, отправивший выражение
 }
 
-//запрос на вычисление
+// результат рационального режима в виде дроби
+type Fraction struct {
+	Numerator   string `json:"numerator"`
+	Denominator string `json:"denominator"`
+}
+
+// запрос на вычисление
 type CalculateRequest struct {
-	Expression string `json:"expression" binding:"required"`
+	Expression string             `json:"expression" binding:"required"`
+	Variables  map[string]float64 `json:"variables,omitempty"` // значения переменных, используемых в выражении
+	Mode       CalculationMode    `json:"mode,omitempty"`      // по умолчанию float
+	Scale      int                `json:"scale,omitempty"`     // знаков после запятой в десятичном режиме
+	OwnerID    uint               `json:"-"`                   // заполняется из токена пользователя
 }
 
 // вычислительная задача
 type Task struct {
-	ID            string   `json:"id"`
-	Arg1          string   `json:"arg1"`
-	Arg2          string   `json:"arg2"`
-	Operation     string   `json:"operation"`
-	OperationTime int64    `json:"operation_time"`
-	Result        *float64 `json:"result,omitempty"`
-	ExpressionID  string   `json:"expression_id,omitempty"` // ID выражения, к которому относится задача
-	Error         *string  `json:"error,omitempty"`         // Поле для хранения ошибки выполнения задачи
+	ID            string          `json:"id"`
+	Arg1          string          `json:"arg1"`
+	Arg2          string          `json:"arg2"`
+	Args          []string        `json:"args,omitempty"` // аргументы вызова функции, Arg1/Arg2 при этом не используются
+	Operation     string          `json:"operation"`
+	OperationTime int64           `json:"operation_time"`
+	Result        *float64        `json:"result,omitempty"`
+	Value         string          `json:"value,omitempty"`         // точный результат в десятичном и рациональном режимах
+	Mode          CalculationMode `json:"mode,omitempty"`          // режим вычисления, пустой означает float
+	Scale         int             `json:"scale,omitempty"`         // знаков после запятой в десятичном режиме
+	ExpressionID  string          `json:"expression_id,omitempty"` // ID выражения, к которому относится задача
+	LeaseID       string          `json:"lease_id,omitempty"`      // аренда, под которой задача выдана агенту
+	Error         *string         `json:"error,omitempty"`         // Поле для хранения ошибки выполнения задачи
 }
 
 // результат выполнения задачи
 type TaskResult struct {
-	ID     string  `json:"id" binding:"required"`
-	Result float64 `json:"result" binding:"required"`
-	Error  *string `json:"error,omitempty"`
+	ID      string  `json:"id" binding:"required"`
+	Result  float64 `json:"result" binding:"required"` // в точных режимах 0, если значение вне диапазона float64
+	Value   string  `json:"value,omitempty"`           // точный результат в десятичном и рациональном режимах
+	LeaseID string  `json:"lease_id,omitempty"`        // аренда из выданной задачи; результат по чужой или истёкшей аренде отклоняется
+	Error   *string `json:"error,omitempty"`
+}
+
+// аренда задачи агентом: задача, не вернувшаяся до Deadline, выдаётся снова
+type TaskLease struct {
+	TaskID       string    `json:"task_id"`
+	LeaseID      string    `json:"lease_id"`
+	ExpressionID string    `json:"expression_id,omitempty"`
+	AgentID      string    `json:"agent_id,omitempty"` // пустой у незарегистрированных агентов
+	Operation    string    `json:"operation"`
+	Attempt      int       `json:"attempt"` // номер выдачи задачи, начиная с 1
+	IssuedAt     time.Time `json:"issued_at"`
+	Deadline     time.Time `json:"deadline"`
+}
+
+// ответ со списком активных аренд
+type LeasesResponse struct {
+	Leases []TaskLease `json:"leases"`
 }
 
 // ответ со списком выражений
@@ -53,7 +105,33 @@ type ExpressionResponse struct {
 	Expression Expression `json:"expression"`
 }
 
+// ответ с описанием ошибки; для ошибок разбора выражения заполняются
+// код, позиция (смещение в байтах) и текст ошибочного токена
+type ErrorResponse struct {
+	Error    string `json:"error"`
+	Code     string `json:"code,omitempty"`
+	Position *int   `json:"position,omitempty"`
+	Token    string `json:"token,omitempty"`
+}
+
 // ответ с задачей
 type TaskResponse struct {
 	Task Task `json:"task"`
 }
+
+// ответ с пачкой задач на GET /internal/tasks
+type TasksResponse struct {
+	Tasks []Task `json:"tasks"`
+}
+
+// итог приёма одного результата из пачки: status "success" или "error"
+type TaskResultStatus struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ответ на POST /internal/results в порядке присланных результатов
+type TaskResultsResponse struct {
+	Results []TaskResultStatus `json:"results"`
+}
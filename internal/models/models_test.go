@@ -182,3 +182,26 @@ func TestExpressionResponseJSON(t *testing.T) {
 		t.Errorf("Expression Result mismatch: got %v, want %v", unmarshalled.Expression.Result, expr.Result)
 	}
 }
+
+func TestNormalizeLogin(t *testing.T) {
+	tests := []struct {
+		name  string
+		login string
+		want  string
+	}{
+		{"уже нормализован", "alice", "alice"},
+		{"регистр", "Alice", "alice"},
+		{"пробелы по краям", "  alice\t", "alice"},
+		{"пробел внутри сохраняется", "Alice Smith", "alice smith"},
+		{"кириллица", "Алиса", "алиса"},
+		{"только пробелы", "   ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeLogin(tt.login); got != tt.want {
+				t.Errorf("NormalizeLogin(%q) = %q, want %q", tt.login, got, tt.want)
+			}
+		})
+	}
+}
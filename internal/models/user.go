@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type User struct {
 	ID           uint      `json:"id" gorm:"primaryKey"`
@@ -8,3 +11,10 @@ type User struct {
 	PasswordHash string    `json:"-" gorm:"not null"` // Не отправляем хеш пароля клиенту
 	CreatedAt    time.Time `json:"created_at"`
 }
+
+// NormalizeLogin приводит логин к виду, в котором он хранится:
+// без пробелов по краям и в нижнем регистре, чтобы "Alice" и "alice"
+// считались одним пользователем.
+func NormalizeLogin(login string) string {
+	return strings.ToLower(strings.TrimSpace(login))
+}
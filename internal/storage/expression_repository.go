@@ -0,0 +1,22 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+)
+
+// ExpressionRepository хранит выражения пользователей между перезапусками сервиса
+type ExpressionRepository interface {
+	// SaveExpression создаёт выражение или обновляет существующее с тем же ID
+	SaveExpression(ctx context.Context, expr *models.Expression) error
+	GetExpression(ctx context.Context, id string) (*models.Expression, error)
+	ListExpressions(ctx context.Context) ([]models.Expression, error)
+}
+
+// TaskRepository хранит задачи выражений
+type TaskRepository interface {
+	// SaveTask создаёт задачу или обновляет существующую с тем же ID
+	SaveTask(ctx context.Context, task *models.Task) error
+	ListTasks(ctx context.Context) ([]models.Task, error)
+}
@@ -0,0 +1,163 @@
+// Package memory хранит данные сервиса в памяти процесса: для тестов
+// и запуска без базы данных. Репозитории безопасны для конкурентного доступа
+// и возвращают копии, чтобы вызывающий код не менял сохранённые данные.
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+	"github.com/superlogarifm/goCalc-v3/internal/storage"
+)
+
+type UserRepository struct {
+	mu     sync.RWMutex
+	users  map[string]models.User
+	nextID uint
+}
+
+func NewUserRepository() *UserRepository {
+	return &UserRepository{users: make(map[string]models.User)}
+}
+
+func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user.Login = models.NormalizeLogin(user.Login)
+	if _, exists := r.users[user.Login]; exists {
+		return storage.ErrUserExists
+	}
+	r.nextID++
+	user.ID = r.nextID
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+	}
+	r.users[user.Login] = *user
+	return nil
+}
+
+func (r *UserRepository) GetUserByLogin(ctx context.Context, login string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[models.NormalizeLogin(login)]
+	if !ok {
+		return nil, storage.ErrUserNotFound
+	}
+	return &user, nil
+}
+
+type ExpressionRepository struct {
+	mu          sync.RWMutex
+	expressions map[string]models.Expression
+	order       []string // ID в порядке создания
+}
+
+func NewExpressionRepository() *ExpressionRepository {
+	return &ExpressionRepository{expressions: make(map[string]models.Expression)}
+}
+
+func (r *ExpressionRepository) SaveExpression(ctx context.Context, expr *models.Expression) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.expressions[expr.ID]; !exists {
+		r.order = append(r.order, expr.ID)
+	}
+	r.expressions[expr.ID] = copyExpression(*expr)
+	return nil
+}
+
+func (r *ExpressionRepository) GetExpression(ctx context.Context, id string) (*models.Expression, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	expr, ok := r.expressions[id]
+	if !ok {
+		return nil, storage.ErrExpressionNotFound
+	}
+	expr = copyExpression(expr)
+	return &expr, nil
+}
+
+func (r *ExpressionRepository) ListExpressions(ctx context.Context) ([]models.Expression, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	expressions := make([]models.Expression, 0, len(r.order))
+	for _, id := range r.order {
+		expressions = append(expressions, copyExpression(r.expressions[id]))
+	}
+	return expressions, nil
+}
+
+func copyExpression(expr models.Expression) models.Expression {
+	if expr.Result != nil {
+		result := *expr.Result
+		expr.Result = &result
+	}
+	if expr.Fraction != nil {
+		fraction := *expr.Fraction
+		expr.Fraction = &fraction
+	}
+	if expr.Variables != nil {
+		variables := make(map[string]float64, len(expr.Variables))
+		for name, value := range expr.Variables {
+			variables[name] = value
+		}
+		expr.Variables = variables
+	}
+	return expr
+}
+
+type TaskRepository struct {
+	mu    sync.RWMutex
+	tasks map[string]models.Task
+	order []string
+}
+
+func NewTaskRepository() *TaskRepository {
+	return &TaskRepository{tasks: make(map[string]models.Task)}
+}
+
+func (r *TaskRepository) SaveTask(ctx context.Context, task *models.Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.tasks[task.ID]; !exists {
+		r.order = append(r.order, task.ID)
+	}
+	r.tasks[task.ID] = copyTask(*task)
+	return nil
+}
+
+func (r *TaskRepository) ListTasks(ctx context.Context) ([]models.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tasks := make([]models.Task, 0, len(r.order))
+	for _, id := range r.order {
+		tasks = append(tasks, copyTask(r.tasks[id]))
+	}
+	return tasks, nil
+}
+
+func copyTask(task models.Task) models.Task {
+	if task.Result != nil {
+		result := *task.Result
+		task.Result = &result
+	}
+	if task.Error != nil {
+		errMsg := *task.Error
+		task.Error = &errMsg
+	}
+	if task.Args != nil {
+		task.Args = append([]string(nil), task.Args...)
+	}
+	// аренды живут только в памяти менеджера задач
+	task.LeaseID = ""
+	return task
+}
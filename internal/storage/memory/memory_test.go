@@ -0,0 +1,26 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/superlogarifm/goCalc-v3/internal/storage"
+	"github.com/superlogarifm/goCalc-v3/internal/storage/storagetest"
+)
+
+func TestUserRepository(t *testing.T) {
+	storagetest.RunUserRepositoryTests(t, func(t *testing.T) storage.UserRepository {
+		return NewUserRepository()
+	})
+}
+
+func TestExpressionRepository(t *testing.T) {
+	storagetest.RunExpressionRepositoryTests(t, func(t *testing.T) storage.ExpressionRepository {
+		return NewExpressionRepository()
+	})
+}
+
+func TestTaskRepository(t *testing.T) {
+	storagetest.RunTaskRepositoryTests(t, func(t *testing.T) (storage.ExpressionRepository, storage.TaskRepository) {
+		return NewExpressionRepository(), NewTaskRepository()
+	})
+}
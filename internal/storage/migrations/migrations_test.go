@@ -0,0 +1,246 @@
+package migrations
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "calc.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("gorm.Open() error = %v", err)
+	}
+	return db
+}
+
+func TestLoad_DialectsMatch(t *testing.T) {
+	pg, err := load("postgres")
+	if err != nil {
+		t.Fatalf("load(postgres) error = %v", err)
+	}
+	lite, err := load("sqlite")
+	if err != nil {
+		t.Fatalf("load(sqlite) error = %v", err)
+	}
+	if len(pg) == 0 || len(pg) != len(lite) {
+		t.Fatalf("postgres has %d migrations, sqlite has %d", len(pg), len(lite))
+	}
+	for i := range pg {
+		if pg[i].Version != i+1 {
+			t.Errorf("migration %d has version %d, want consecutive versions", i, pg[i].Version)
+		}
+		if pg[i].Version != lite[i].Version || pg[i].Name != lite[i].Name {
+			t.Errorf("migration %d: postgres %d_%s, sqlite %d_%s", i, pg[i].Version, pg[i].Name, lite[i].Version, lite[i].Name)
+		}
+	}
+
+	if _, err := load("mysql"); err == nil {
+		t.Error("load(mysql) error = nil, want error")
+	}
+}
+
+func TestSplitFileName(t *testing.T) {
+	tests := []struct {
+		name          string
+		file          string
+		wantBase      string
+		wantDirection string
+		wantOK        bool
+	}{
+		{"up", "0001_create_users.up.sql", "0001_create_users", "up", true},
+		{"down", "0001_create_users.down.sql", "0001_create_users", "down", true},
+		{"без направления", "0001_create_users.sql", "", "", false},
+		{"не sql", "0001_create_users.up.txt", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, direction, ok := splitFileName(tt.file)
+			if base != tt.wantBase || direction != tt.wantDirection || ok != tt.wantOK {
+				t.Errorf("splitFileName(%q) = %q, %q, %v, want %q, %q, %v",
+					tt.file, base, direction, ok, tt.wantBase, tt.wantDirection, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestMigrator_UpDown(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	m, err := New(db)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	total := len(m.Migrations())
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if len(applied) != total {
+		t.Errorf("Up() applied %d migrations, want %d", len(applied), total)
+	}
+	for _, table := range []string{"users", "expressions", "tasks", "schema_migrations"} {
+		if !db.Migrator().HasTable(table) {
+			t.Errorf("table %s is missing after Up()", table)
+		}
+	}
+
+	// повторный запуск ничего не делает
+	if applied, err := m.Up(ctx); err != nil || len(applied) != 0 {
+		t.Errorf("second Up() = %v, %v, want nothing applied", applied, err)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			t.Errorf("migration %d_%s is not applied", s.Version, s.Name)
+		}
+	}
+
+	last, err := m.Down(ctx)
+	if err != nil || last == nil || last.Version != total {
+		t.Fatalf("Down() = %+v, %v, want migration %d", last, err, total)
+	}
+	if pending, err := m.Pending(ctx); err != nil || len(pending) != 1 || pending[0].Version != total {
+		t.Errorf("Pending() = %+v, %v, want migration %d", pending, err, total)
+	}
+
+	for i := 1; i < total; i++ {
+		if _, err := m.Down(ctx); err != nil {
+			t.Fatalf("Down() error = %v", err)
+		}
+	}
+	for _, table := range []string{"users", "expressions", "tasks"} {
+		if db.Migrator().HasTable(table) {
+			t.Errorf("table %s remains after rolling back all migrations", table)
+		}
+	}
+	if last, err := m.Down(ctx); err != nil || last != nil {
+		t.Errorf("Down() with nothing applied = %+v, %v, want nil, nil", last, err)
+	}
+
+	if applied, err := m.Up(ctx); err != nil || len(applied) != total {
+		t.Errorf("Up() after rollback = %d migrations, %v, want %d", len(applied), err, total)
+	}
+}
+
+func TestMigrator_CaseInsensitiveLogin(t *testing.T) {
+	db := openTestDB(t)
+	m, err := New(db)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+
+	if err := db.Exec("INSERT INTO users (login, password_hash) VALUES (?, ?)", "alice", "hash").Error; err != nil {
+		t.Fatalf("insert error = %v", err)
+	}
+	if err := db.Exec("INSERT INTO users (login, password_hash) VALUES (?, ?)", "Alice", "hash").Error; err == nil {
+		t.Error("inserted a login that differs only in case")
+	}
+}
+
+func TestMigrator_CaseDuplicateLogins(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	m, err := New(db)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	// база прежней версии: схема без индекса по LOWER(login)
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if last, err := m.Down(ctx); err != nil || last.Name != "users_login_case_insensitive" {
+		t.Fatalf("Down() = %+v, %v, want the login index migration", last, err)
+	}
+	for _, login := range []string{"alice", "Alice", "Bob"} {
+		if err := db.Exec("INSERT INTO users (login, password_hash) VALUES (?, ?)", login, "hash").Error; err != nil {
+			t.Fatalf("insert %s error = %v", login, err)
+		}
+	}
+
+	_, err = m.Up(ctx)
+	if err == nil || !strings.Contains(err.Error(), "differ only in case") {
+		t.Fatalf("Up() error = %v, want error about case duplicates", err)
+	}
+	if pending, err := m.Pending(ctx); err != nil || len(pending) != 1 {
+		t.Errorf("Pending() = %+v, %v, want the failed migration", pending, err)
+	}
+
+	// дубль объединён вручную, миграция применяется и приводит логины к нижнему регистру
+	if err := db.Exec("DELETE FROM users WHERE login = ?", "Alice").Error; err != nil {
+		t.Fatalf("delete error = %v", err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up() after merge error = %v", err)
+	}
+	var logins []string
+	if err := db.Raw("SELECT login FROM users ORDER BY login").Scan(&logins).Error; err != nil {
+		t.Fatalf("select error = %v", err)
+	}
+	if len(logins) != 2 || logins[0] != "alice" || logins[1] != "bob" {
+		t.Errorf("logins = %v, want [alice bob]", logins)
+	}
+}
+
+func TestSplitScript(t *testing.T) {
+	script := `-- комментарий
+CREATE TABLE a (id INTEGER);
+
+DO $$
+BEGIN
+    PERFORM 1;
+END
+$$;
+DROP TABLE a;`
+	statements := splitScript(script)
+	if len(statements) != 3 {
+		t.Fatalf("splitScript() = %q, want 3 statements", statements)
+	}
+	if !strings.Contains(statements[1], "PERFORM 1;") || !strings.HasSuffix(strings.TrimSpace(statements[1]), "$$;") {
+		t.Errorf("splitScript() split the $$ block: %q", statements[1])
+	}
+}
+
+func TestMigrator_FailedMigrationIsNotRecorded(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	m, err := New(db)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	m.migrations = append(m.Migrations(), Migration{
+		Version: len(m.migrations) + 1,
+		Name:    "broken",
+		Up:      "CREATE TABLE broken (id INTEGER);\nNOT SQL;",
+		Down:    "DROP TABLE broken;",
+	})
+
+	applied, err := m.Up(ctx)
+	if err == nil {
+		t.Fatal("Up() error = nil, want error from broken migration")
+	}
+	if len(applied) != len(m.migrations)-1 {
+		t.Errorf("Up() applied %d migrations before failure, want %d", len(applied), len(m.migrations)-1)
+	}
+	if db.Migrator().HasTable("broken") {
+		t.Error("partially applied migration was not rolled back")
+	}
+	if pending, err := m.Pending(ctx); err != nil || len(pending) != 1 || pending[0].Name != "broken" {
+		t.Errorf("Pending() = %+v, %v, want only the broken migration", pending, err)
+	}
+}
@@ -0,0 +1,7 @@
+-- IF NOT EXISTS позволяет принять базу, созданную прежним AutoMigrate
+CREATE TABLE IF NOT EXISTS users (
+    id            BIGSERIAL PRIMARY KEY,
+    login         TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    created_at    TIMESTAMPTZ
+);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS expressions (
+    id         TEXT PRIMARY KEY,
+    owner_id   BIGINT NOT NULL,
+    input      TEXT NOT NULL,
+    status     TEXT NOT NULL,
+    result     DOUBLE PRECISION,
+    value      TEXT,
+    fraction   TEXT,
+    error_msg  TEXT,
+    variables  TEXT,
+    mode       TEXT,
+    scale      BIGINT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_expressions_owner_id ON expressions (owner_id);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS tasks (
+    id             TEXT PRIMARY KEY,
+    expression_id  TEXT NOT NULL,
+    arg1           TEXT,
+    arg2           TEXT,
+    args           TEXT,
+    operation      TEXT NOT NULL,
+    operation_time BIGINT,
+    result         DOUBLE PRECISION,
+    value          TEXT,
+    mode           TEXT,
+    scale          BIGINT,
+    error          TEXT,
+    created_at     TIMESTAMPTZ,
+    updated_at     TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_tasks_expression_id ON tasks (expression_id);
@@ -0,0 +1,16 @@
+-- логины хранятся в нижнем регистре; индекс не даёт появиться дублям
+-- вида "Alice"/"alice" среди записей, созданных до нормализации.
+-- Если такие дубли уже есть, миграция останавливается: какую из учётных
+-- записей оставить, решает администратор (см. README).
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM users GROUP BY LOWER(login) HAVING COUNT(*) > 1) THEN
+        RAISE EXCEPTION 'users contains logins that differ only in case, merge them before applying this migration'
+            USING HINT = 'SELECT LOWER(login), COUNT(*) FROM users GROUP BY LOWER(login) HAVING COUNT(*) > 1';
+    END IF;
+END
+$$;
+
+UPDATE users SET login = LOWER(login) WHERE login <> LOWER(login);
+
+CREATE UNIQUE INDEX idx_users_login_lower ON users (LOWER(login));
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS expressions (
+    id         TEXT PRIMARY KEY,
+    owner_id   INTEGER NOT NULL,
+    input      TEXT NOT NULL,
+    status     TEXT NOT NULL,
+    result     REAL,
+    value      TEXT,
+    fraction   TEXT,
+    error_msg  TEXT,
+    variables  TEXT,
+    mode       TEXT,
+    scale      INTEGER,
+    created_at DATETIME,
+    updated_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_expressions_owner_id ON expressions (owner_id);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS tasks (
+    id             TEXT PRIMARY KEY,
+    expression_id  TEXT NOT NULL,
+    arg1           TEXT,
+    arg2           TEXT,
+    args           TEXT,
+    operation      TEXT NOT NULL,
+    operation_time INTEGER,
+    result         REAL,
+    value          TEXT,
+    mode           TEXT,
+    scale          INTEGER,
+    error          TEXT,
+    created_at     DATETIME,
+    updated_at     DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_tasks_expression_id ON tasks (expression_id);
@@ -0,0 +1,19 @@
+-- логины хранятся в нижнем регистре; индекс не даёт появиться дублям
+-- вида "Alice"/"alice" среди записей, созданных до нормализации.
+-- Если такие дубли уже есть, миграция останавливается: какую из учётных
+-- записей оставить, решает администратор (см. README). В sqlite нет
+-- процедурных блоков, поэтому ошибку поднимает временный триггер.
+CREATE TEMP TABLE login_case_check (duplicates INTEGER);
+
+CREATE TEMP TRIGGER login_case_check_abort BEFORE INSERT ON login_case_check
+WHEN NEW.duplicates > 0
+BEGIN SELECT RAISE(ABORT, 'users contains logins that differ only in case, merge them before applying this migration'); END;
+
+INSERT INTO login_case_check
+SELECT COUNT(*) FROM (SELECT 1 FROM users GROUP BY LOWER(login) HAVING COUNT(*) > 1);
+
+DROP TABLE login_case_check;
+
+UPDATE users SET login = LOWER(login) WHERE login <> LOWER(login);
+
+CREATE UNIQUE INDEX idx_users_login_lower ON users (LOWER(login));
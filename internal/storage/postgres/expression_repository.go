@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+	"github.com/superlogarifm/goCalc-v3/internal/storage"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// expressionRecord — строка таблицы expressions. Переменные и дробь
+// хранятся в JSON, чтобы схема не зависела от их состава.
+type expressionRecord struct {
+	ID        string `gorm:"primaryKey"`
+	OwnerID   uint   `gorm:"index;not null"`
+	Input     string `gorm:"not null"`
+	Status    string `gorm:"not null"`
+	Result    *float64
+	Value     string
+	Fraction  string
+	ErrorMsg  string
+	Variables string
+	Mode      string
+	Scale     int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (expressionRecord) TableName() string { return "expressions" }
+
+func newExpressionRecord(expr *models.Expression) (*expressionRecord, error) {
+	record := &expressionRecord{
+		ID:       expr.ID,
+		OwnerID:  expr.OwnerID,
+		Input:    expr.Input,
+		Status:   string(expr.Status),
+		Result:   expr.Result,
+		Value:    expr.Value,
+		ErrorMsg: expr.ErrorMsg,
+		Mode:     string(expr.Mode),
+		Scale:    expr.Scale,
+	}
+	if expr.Fraction != nil {
+		data, err := json.Marshal(expr.Fraction)
+		if err != nil {
+			return nil, err
+		}
+		record.Fraction = string(data)
+	}
+	if len(expr.Variables) > 0 {
+		data, err := json.Marshal(expr.Variables)
+		if err != nil {
+			return nil, err
+		}
+		record.Variables = string(data)
+	}
+	return record, nil
+}
+
+func (r *expressionRecord) toModel() (*models.Expression, error) {
+	expr := &models.Expression{
+		ID:       r.ID,
+		OwnerID:  r.OwnerID,
+		Input:    r.Input,
+		Status:   models.ExpressionStatus(r.Status),
+		Result:   r.Result,
+		Value:    r.Value,
+		ErrorMsg: r.ErrorMsg,
+		Mode:     models.CalculationMode(r.Mode),
+		Scale:    r.Scale,
+	}
+	if r.Fraction != "" {
+		if err := json.Unmarshal([]byte(r.Fraction), &expr.Fraction); err != nil {
+			return nil, err
+		}
+	}
+	if r.Variables != "" {
+		if err := json.Unmarshal([]byte(r.Variables), &expr.Variables); err != nil {
+			return nil, err
+		}
+	}
+	return expr, nil
+}
+
+type PGExpressionRepository struct {
+	db *gorm.DB
+}
+
+func NewPGExpressionRepository(db *gorm.DB) *PGExpressionRepository {
+	return &PGExpressionRepository{db: db}
+}
+
+func (r *PGExpressionRepository) SaveExpression(ctx context.Context, expr *models.Expression) error {
+	record, err := newExpressionRecord(expr)
+	if err != nil {
+		return err
+	}
+	// upsert сохраняет created_at исходной записи
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(record).Error
+}
+
+func (r *PGExpressionRepository) GetExpression(ctx context.Context, id string) (*models.Expression, error) {
+	var record expressionRecord
+	result := r.db.WithContext(ctx).Where("id = ?", id).First(&record)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, storage.ErrExpressionNotFound
+		}
+		return nil, result.Error
+	}
+	return record.toModel()
+}
+
+func (r *PGExpressionRepository) ListExpressions(ctx context.Context) ([]models.Expression, error) {
+	var records []expressionRecord
+	if err := r.db.WithContext(ctx).Order("created_at").Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	expressions := make([]models.Expression, 0, len(records))
+	for i := range records {
+		expr, err := records[i].toModel()
+		if err != nil {
+			return nil, err
+		}
+		expressions = append(expressions, *expr)
+	}
+	return expressions, nil
+}
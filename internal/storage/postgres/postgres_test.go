@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/superlogarifm/goCalc-v3/internal/storage"
+	"github.com/superlogarifm/goCalc-v3/internal/storage/migrations"
+	"github.com/superlogarifm/goCalc-v3/internal/storage/storagetest"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB подключается к базе из TEST_DATABASE_URL и пересоздаёт схему миграциями.
+// Без переменной тесты пропускаются: база должна быть отдельной, данные в ней удаляются.
+func openTestDB(t *testing.T) (*PGUserRepository, *PGExpressionRepository, *PGTaskRepository) {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := gorm.Open(postgres.Open(url), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("gorm.Open() error = %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if err := db.Migrator().DropTable("tasks", "expressions", "users", "schema_migrations"); err != nil {
+		t.Fatalf("DropTable() error = %v", err)
+	}
+	m, err := migrations.New(db)
+	if err != nil {
+		t.Fatalf("migrations.New() error = %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	return NewPGUserRepository(db), NewPGExpressionRepository(db), NewPGTaskRepository(db)
+}
+
+func TestUserRepository(t *testing.T) {
+	storagetest.RunUserRepositoryTests(t, func(t *testing.T) storage.UserRepository {
+		users, _, _ := openTestDB(t)
+		return users
+	})
+}
+
+func TestExpressionRepository(t *testing.T) {
+	storagetest.RunExpressionRepositoryTests(t, func(t *testing.T) storage.ExpressionRepository {
+		_, expressions, _ := openTestDB(t)
+		return expressions
+	})
+}
+
+func TestTaskRepository(t *testing.T) {
+	storagetest.RunTaskRepositoryTests(t, func(t *testing.T) (storage.ExpressionRepository, storage.TaskRepository) {
+		_, expressions, tasks := openTestDB(t)
+		return expressions, tasks
+	})
+}
+
+func TestIsDuplicateKey(t *testing.T) {
+	db := &gorm.DB{Config: &gorm.Config{Dialector: postgres.Dialector{}}}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"нарушение уникальности", &pgconn.PgError{Code: "23505"}, true},
+		{"уже переведённая ошибка", gorm.ErrDuplicatedKey, true},
+		{"другой код", &pgconn.PgError{Code: "23502"}, false},
+		{"не ошибка БД", errors.New("connection refused"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDuplicateKey(db, tt.err); got != tt.want {
+				t.Errorf("isDuplicateKey(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
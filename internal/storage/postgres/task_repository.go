@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// taskRecord — строка таблицы tasks; аргументы функции хранятся в JSON.
+// Аренды не сохраняются: после перезапуска задачи выдаются заново.
+type taskRecord struct {
+	ID            string `gorm:"primaryKey"`
+	ExpressionID  string `gorm:"index;not null"`
+	Arg1          string
+	Arg2          string
+	Args          string
+	Operation     string `gorm:"not null"`
+	OperationTime int64
+	Result        *float64
+	Value         string
+	Mode          string
+	Scale         int
+	Error         *string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (taskRecord) TableName() string { return "tasks" }
+
+func newTaskRecord(task *models.Task) (*taskRecord, error) {
+	record := &taskRecord{
+		ID:            task.ID,
+		ExpressionID:  task.ExpressionID,
+		Arg1:          task.Arg1,
+		Arg2:          task.Arg2,
+		Operation:     task.Operation,
+		OperationTime: task.OperationTime,
+		Result:        task.Result,
+		Value:         task.Value,
+		Mode:          string(task.Mode),
+		Scale:         task.Scale,
+		Error:         task.Error,
+	}
+	if task.Args != nil {
+		data, err := json.Marshal(task.Args)
+		if err != nil {
+			return nil, err
+		}
+		record.Args = string(data)
+	}
+	return record, nil
+}
+
+func (r *taskRecord) toModel() (*models.Task, error) {
+	task := &models.Task{
+		ID:            r.ID,
+		ExpressionID:  r.ExpressionID,
+		Arg1:          r.Arg1,
+		Arg2:          r.Arg2,
+		Operation:     r.Operation,
+		OperationTime: r.OperationTime,
+		Result:        r.Result,
+		Value:         r.Value,
+		Mode:          models.CalculationMode(r.Mode),
+		Scale:         r.Scale,
+		Error:         r.Error,
+	}
+	if r.Args != "" {
+		if err := json.Unmarshal([]byte(r.Args), &task.Args); err != nil {
+			return nil, err
+		}
+	}
+	return task, nil
+}
+
+type PGTaskRepository struct {
+	db *gorm.DB
+}
+
+func NewPGTaskRepository(db *gorm.DB) *PGTaskRepository {
+	return &PGTaskRepository{db: db}
+}
+
+func (r *PGTaskRepository) SaveTask(ctx context.Context, task *models.Task) error {
+	record, err := newTaskRecord(task)
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(record).Error
+}
+
+func (r *PGTaskRepository) ListTasks(ctx context.Context) ([]models.Task, error) {
+	var records []taskRecord
+	if err := r.db.WithContext(ctx).Order("created_at").Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	tasks := make([]models.Task, 0, len(records))
+	for i := range records {
+		task, err := records[i].toModel()
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, *task)
+	}
+	return tasks, nil
+}
@@ -19,8 +19,12 @@ func NewPGUserRepository(db *gorm.DB) *PGUserRepository {
 }
 
 func (r *PGUserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	user.Login = models.NormalizeLogin(user.Login)
 	result := r.db.WithContext(ctx).Create(user)
 	if result.Error != nil {
+		if isDuplicateKey(r.db, result.Error) {
+			return storage.ErrUserExists
+		}
 		return result.Error
 	}
 	return nil
@@ -28,7 +32,8 @@ func (r *PGUserRepository) CreateUser(ctx context.Context, user *models.User) er
 
 func (r *PGUserRepository) GetUserByLogin(ctx context.Context, login string) (*models.User, error) {
 	var user models.User
-	result := r.db.WithContext(ctx).Where("login = ?", login).First(&user)
+	// LOWER находит и записи, созданные до нормализации логинов
+	result := r.db.WithContext(ctx).Where("LOWER(login) = ?", models.NormalizeLogin(login)).Order("id").First(&user)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, storage.ErrUserNotFound
@@ -38,6 +43,16 @@ func (r *PGUserRepository) GetUserByLogin(ctx context.Context, login string) (*m
 	return &user, nil
 }
 
-func (r *PGUserRepository) AutoMigrate() error {
-	return r.db.AutoMigrate(&models.User{})
+// isDuplicateKey сообщает о нарушении ограничения уникальности.
+// Ошибку переводит диалект БД (SQLSTATE 23505 в PostgreSQL,
+// SQLITE_CONSTRAINT_UNIQUE в SQLite), поэтому проверка работает
+// и без TranslateError в конфигурации gorm.
+func isDuplicateKey(db *gorm.DB, err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok {
+		return errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey)
+	}
+	return false
 }
@@ -0,0 +1,29 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/superlogarifm/goCalc-v3/internal/storage"
+	"github.com/superlogarifm/goCalc-v3/internal/storage/storagetest"
+)
+
+func TestUserRepositoryConformance(t *testing.T) {
+	storagetest.RunUserRepositoryTests(t, func(t *testing.T) storage.UserRepository {
+		users, _, _ := openTestDB(t)
+		return users
+	})
+}
+
+func TestExpressionRepositoryConformance(t *testing.T) {
+	storagetest.RunExpressionRepositoryTests(t, func(t *testing.T) storage.ExpressionRepository {
+		_, expressions, _ := openTestDB(t)
+		return expressions
+	})
+}
+
+func TestTaskRepositoryConformance(t *testing.T) {
+	storagetest.RunTaskRepositoryTests(t, func(t *testing.T) (storage.ExpressionRepository, storage.TaskRepository) {
+		_, expressions, tasks := openTestDB(t)
+		return expressions, tasks
+	})
+}
@@ -0,0 +1,61 @@
+// Package sqlite хранит данные сервиса в локальном файле SQLite. Драйвер
+// написан на чистом Go, поэтому сервис собирается в один бинарник без cgo.
+package sqlite
+
+import (
+	"strings"
+
+	"github.com/superlogarifm/goCalc-v3/internal/storage/postgres"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// Open открывает базу по пути к файлу или DSN вида file:... .
+// SQLite допускает одного писателя, поэтому соединение одно, а конкурирующие
+// запросы ждут блокировку вместо ошибки "database is locked".
+func Open(dsn string) (*gorm.DB, error) {
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	dsn += separator + "_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"
+
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// Схема и запросы совпадают с PostgreSQL, поэтому репозитории SQLite
+// используют GORM-реализации из пакета postgres.
+
+type UserRepository struct {
+	*postgres.PGUserRepository
+}
+
+func NewUserRepository(db *gorm.DB) *UserRepository {
+	return &UserRepository{PGUserRepository: postgres.NewPGUserRepository(db)}
+}
+
+type ExpressionRepository struct {
+	*postgres.PGExpressionRepository
+}
+
+func NewExpressionRepository(db *gorm.DB) *ExpressionRepository {
+	return &ExpressionRepository{PGExpressionRepository: postgres.NewPGExpressionRepository(db)}
+}
+
+type TaskRepository struct {
+	*postgres.PGTaskRepository
+}
+
+func NewTaskRepository(db *gorm.DB) *TaskRepository {
+	return &TaskRepository{PGTaskRepository: postgres.NewPGTaskRepository(db)}
+}
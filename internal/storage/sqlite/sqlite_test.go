@@ -0,0 +1,136 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/superlogarifm/goCalc-v3/internal/calculator"
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+	"github.com/superlogarifm/goCalc-v3/internal/storage"
+	"github.com/superlogarifm/goCalc-v3/internal/storage/migrations"
+)
+
+func openTestDB(t *testing.T) (*UserRepository, *ExpressionRepository, *TaskRepository) {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "calc.db"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	m, err := migrations.New(db)
+	if err != nil {
+		t.Fatalf("migrations.New() error = %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	return NewUserRepository(db), NewExpressionRepository(db), NewTaskRepository(db)
+}
+
+func TestUserRepository(t *testing.T) {
+	users, _, _ := openTestDB(t)
+	ctx := context.Background()
+
+	if err := users.CreateUser(ctx, &models.User{Login: "alice", PasswordHash: "hash"}); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	user, err := users.GetUserByLogin(ctx, "alice")
+	if err != nil || user.ID == 0 || user.PasswordHash != "hash" {
+		t.Errorf("GetUserByLogin() = %+v, %v", user, err)
+	}
+	if _, err := users.GetUserByLogin(ctx, "bob"); !errors.Is(err, storage.ErrUserNotFound) {
+		t.Errorf("GetUserByLogin() error = %v, want %v", err, storage.ErrUserNotFound)
+	}
+}
+
+func TestExpressionRepository(t *testing.T) {
+	_, expressions, _ := openTestDB(t)
+	ctx := context.Background()
+
+	expr := &models.Expression{
+		ID:        "2",
+		OwnerID:   1,
+		Input:     "x/3",
+		Status:    models.StatusProcessing,
+		Variables: map[string]float64{"x": 1},
+		Mode:      models.ModeRational,
+	}
+	if err := expressions.SaveExpression(ctx, expr); err != nil {
+		t.Fatalf("SaveExpression() error = %v", err)
+	}
+
+	result := 1.0 / 3
+	expr.Status = models.StatusCompleted
+	expr.Result = &result
+	expr.Value = "1/3"
+	expr.Fraction = &models.Fraction{Numerator: "1", Denominator: "3"}
+	if err := expressions.SaveExpression(ctx, expr); err != nil {
+		t.Fatalf("SaveExpression() update error = %v", err)
+	}
+
+	got, err := expressions.GetExpression(ctx, "2")
+	if err != nil {
+		t.Fatalf("GetExpression() error = %v", err)
+	}
+	if got.Status != models.StatusCompleted || got.Value != "1/3" || got.Fraction == nil || got.Fraction.Denominator != "3" ||
+		got.Variables["x"] != 1 || got.OwnerID != 1 || got.Mode != models.ModeRational {
+		t.Errorf("GetExpression() = %+v", got)
+	}
+
+	list, err := expressions.ListExpressions(ctx)
+	if err != nil || len(list) != 1 {
+		t.Errorf("ListExpressions() = %+v, %v, want one expression", list, err)
+	}
+	if _, err := expressions.GetExpression(ctx, "missing"); !errors.Is(err, storage.ErrExpressionNotFound) {
+		t.Errorf("GetExpression() error = %v, want %v", err, storage.ErrExpressionNotFound)
+	}
+}
+
+func TestTaskManagerOnSQLite(t *testing.T) {
+	_, expressions, tasks := openTestDB(t)
+
+	tm, err := calculator.NewTaskManagerWithStorage(expressions, tasks)
+	if err != nil {
+		t.Fatalf("NewTaskManagerWithStorage() error = %v", err)
+	}
+	id, err := tm.CreateExpression(models.CalculateRequest{Expression: "max(1, 2) * (3 + 4)", OwnerID: 1})
+	if err != nil {
+		t.Fatalf("CreateExpression() error = %v", err)
+	}
+	task, _ := tm.GetNextTask()
+	if err := tm.UpdateTaskResult(calculator.EvaluateTask(*task)); err != nil {
+		t.Fatalf("UpdateTaskResult() error = %v", err)
+	}
+
+	if err := tm.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	// перезапуск посреди вычисления
+	tm, err = calculator.NewTaskManagerWithStorage(expressions, tasks)
+	if err != nil {
+		t.Fatalf("NewTaskManagerWithStorage() error = %v", err)
+	}
+	for {
+		task, ok := tm.GetNextTask()
+		if !ok {
+			break
+		}
+		if err := tm.UpdateTaskResult(calculator.EvaluateTask(*task)); err != nil {
+			t.Fatalf("UpdateTaskResult() error = %v", err)
+		}
+	}
+
+	if err := tm.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	expr, err := expressions.GetExpression(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetExpression() error = %v", err)
+	}
+	if expr.Status != models.StatusCompleted || expr.Result == nil || *expr.Result != 14 {
+		t.Errorf("stored expression = %+v, want completed with 14", expr)
+	}
+}
@@ -0,0 +1,318 @@
+// Package storagetest содержит общий набор проверок, который должна проходить
+// каждая реализация репозиториев из пакета storage.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/superlogarifm/goCalc-v3/internal/models"
+	"github.com/superlogarifm/goCalc-v3/internal/storage"
+)
+
+// RunUserRepositoryTests проверяет репозиторий пользователей; newRepo
+// вызывается для каждого подтеста и должен возвращать пустое хранилище.
+func RunUserRepositoryTests(t *testing.T, newRepo func(t *testing.T) storage.UserRepository) {
+	ctx := context.Background()
+
+	t.Run("создание и поиск", func(t *testing.T) {
+		repo := newRepo(t)
+		user := &models.User{Login: "alice", PasswordHash: "hash"}
+		if err := repo.CreateUser(ctx, user); err != nil {
+			t.Fatalf("CreateUser() error = %v", err)
+		}
+		if user.ID == 0 {
+			t.Error("CreateUser() did not assign an ID")
+		}
+
+		got, err := repo.GetUserByLogin(ctx, "alice")
+		if err != nil {
+			t.Fatalf("GetUserByLogin() error = %v", err)
+		}
+		if got.ID != user.ID || got.Login != "alice" || got.PasswordHash != "hash" || got.CreatedAt.IsZero() {
+			t.Errorf("GetUserByLogin() = %+v, want %+v", got, user)
+		}
+	})
+
+	t.Run("разные пользователи получают разные ID", func(t *testing.T) {
+		repo := newRepo(t)
+		alice := &models.User{Login: "alice", PasswordHash: "hash"}
+		bob := &models.User{Login: "bob", PasswordHash: "hash"}
+		if err := repo.CreateUser(ctx, alice); err != nil {
+			t.Fatalf("CreateUser() error = %v", err)
+		}
+		if err := repo.CreateUser(ctx, bob); err != nil {
+			t.Fatalf("CreateUser() error = %v", err)
+		}
+		if alice.ID == bob.ID {
+			t.Errorf("users share ID %d", alice.ID)
+		}
+	})
+
+	t.Run("повторный логин", func(t *testing.T) {
+		repo := newRepo(t)
+		if err := repo.CreateUser(ctx, &models.User{Login: "alice", PasswordHash: "hash"}); err != nil {
+			t.Fatalf("CreateUser() error = %v", err)
+		}
+		err := repo.CreateUser(ctx, &models.User{Login: "alice", PasswordHash: "other"})
+		if !errors.Is(err, storage.ErrUserExists) {
+			t.Fatalf("CreateUser() error = %v, want %v", err, storage.ErrUserExists)
+		}
+		got, err := repo.GetUserByLogin(ctx, "alice")
+		if err != nil || got.PasswordHash != "hash" {
+			t.Errorf("GetUserByLogin() = %+v, %v, want the first user", got, err)
+		}
+	})
+
+	t.Run("логин без учёта регистра и пробелов", func(t *testing.T) {
+		repo := newRepo(t)
+		user := &models.User{Login: "  Alice ", PasswordHash: "hash"}
+		if err := repo.CreateUser(ctx, user); err != nil {
+			t.Fatalf("CreateUser() error = %v", err)
+		}
+		if user.Login != "alice" {
+			t.Errorf("CreateUser() stored login %q, want %q", user.Login, "alice")
+		}
+		if err := repo.CreateUser(ctx, &models.User{Login: "ALICE", PasswordHash: "other"}); !errors.Is(err, storage.ErrUserExists) {
+			t.Errorf("CreateUser() error = %v, want %v", err, storage.ErrUserExists)
+		}
+		got, err := repo.GetUserByLogin(ctx, " aLiCe")
+		if err != nil || got.ID != user.ID {
+			t.Errorf("GetUserByLogin() = %+v, %v, want %+v", got, err, user)
+		}
+	})
+
+	t.Run("неизвестный логин", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.GetUserByLogin(ctx, "nobody"); !errors.Is(err, storage.ErrUserNotFound) {
+			t.Errorf("GetUserByLogin() error = %v, want %v", err, storage.ErrUserNotFound)
+		}
+	})
+
+	t.Run("конкурентная регистрация", func(t *testing.T) {
+		repo := newRepo(t)
+		const attempts = 8
+		errs := make(chan error, attempts)
+		var wg sync.WaitGroup
+		for i := 0; i < attempts; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- repo.CreateUser(ctx, &models.User{Login: "alice", PasswordHash: "hash"})
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		created := 0
+		for err := range errs {
+			switch {
+			case err == nil:
+				created++
+			case !errors.Is(err, storage.ErrUserExists):
+				t.Errorf("CreateUser() error = %v, want nil or %v", err, storage.ErrUserExists)
+			}
+		}
+		if created != 1 {
+			t.Errorf("created %d users with the same login, want 1", created)
+		}
+	})
+}
+
+// RunExpressionRepositoryTests проверяет репозиторий выражений.
+func RunExpressionRepositoryTests(t *testing.T, newRepo func(t *testing.T) storage.ExpressionRepository) {
+	ctx := context.Background()
+
+	t.Run("сохранение и чтение", func(t *testing.T) {
+		repo := newRepo(t)
+		result := 1.0 / 3
+		expr := &models.Expression{
+			ID:        "1",
+			OwnerID:   7,
+			Input:     "x/3",
+			Status:    models.StatusCompleted,
+			Result:    &result,
+			Value:     "1/3",
+			Fraction:  &models.Fraction{Numerator: "1", Denominator: "3"},
+			Variables: map[string]float64{"x": 1},
+			Mode:      models.ModeRational,
+		}
+		if err := repo.SaveExpression(ctx, expr); err != nil {
+			t.Fatalf("SaveExpression() error = %v", err)
+		}
+
+		got, err := repo.GetExpression(ctx, "1")
+		if err != nil {
+			t.Fatalf("GetExpression() error = %v", err)
+		}
+		if got.OwnerID != 7 || got.Input != "x/3" || got.Status != models.StatusCompleted ||
+			got.Result == nil || *got.Result != result || got.Value != "1/3" ||
+			got.Fraction == nil || *got.Fraction != *expr.Fraction ||
+			got.Variables["x"] != 1 || got.Mode != models.ModeRational {
+			t.Errorf("GetExpression() = %+v, want %+v", got, expr)
+		}
+	})
+
+	t.Run("повторное сохранение обновляет запись", func(t *testing.T) {
+		repo := newRepo(t)
+		expr := &models.Expression{ID: "1", OwnerID: 1, Input: "1/0", Status: models.StatusProcessing}
+		if err := repo.SaveExpression(ctx, expr); err != nil {
+			t.Fatalf("SaveExpression() error = %v", err)
+		}
+		expr.Status = models.StatusError
+		expr.ErrorMsg = "division by zero"
+		if err := repo.SaveExpression(ctx, expr); err != nil {
+			t.Fatalf("SaveExpression() update error = %v", err)
+		}
+
+		got, err := repo.GetExpression(ctx, "1")
+		if err != nil || got.Status != models.StatusError || got.ErrorMsg != "division by zero" {
+			t.Errorf("GetExpression() = %+v, %v, want updated expression", got, err)
+		}
+		list, err := repo.ListExpressions(ctx)
+		if err != nil || len(list) != 1 {
+			t.Errorf("ListExpressions() = %+v, %v, want one expression", list, err)
+		}
+	})
+
+	t.Run("список в порядке создания", func(t *testing.T) {
+		repo := newRepo(t)
+		for _, id := range []string{"3", "10", "2"} {
+			if err := repo.SaveExpression(ctx, &models.Expression{ID: id, OwnerID: 1, Input: "1+1", Status: models.StatusPending}); err != nil {
+				t.Fatalf("SaveExpression(%s) error = %v", id, err)
+			}
+		}
+		// обновление не меняет позицию выражения
+		if err := repo.SaveExpression(ctx, &models.Expression{ID: "3", OwnerID: 1, Input: "1+1", Status: models.StatusProcessing}); err != nil {
+			t.Fatalf("SaveExpression() update error = %v", err)
+		}
+
+		list, err := repo.ListExpressions(ctx)
+		if err != nil {
+			t.Fatalf("ListExpressions() error = %v", err)
+		}
+		var ids []string
+		for _, expr := range list {
+			ids = append(ids, expr.ID)
+		}
+		if len(ids) != 3 || ids[0] != "3" || ids[1] != "10" || ids[2] != "2" {
+			t.Errorf("ListExpressions() IDs = %v, want [3 10 2]", ids)
+		}
+	})
+
+	t.Run("пустое хранилище", func(t *testing.T) {
+		repo := newRepo(t)
+		list, err := repo.ListExpressions(ctx)
+		if err != nil || len(list) != 0 {
+			t.Errorf("ListExpressions() = %+v, %v, want empty list", list, err)
+		}
+	})
+
+	t.Run("неизвестное выражение", func(t *testing.T) {
+		repo := newRepo(t)
+		if _, err := repo.GetExpression(ctx, "missing"); !errors.Is(err, storage.ErrExpressionNotFound) {
+			t.Errorf("GetExpression() error = %v, want %v", err, storage.ErrExpressionNotFound)
+		}
+	})
+
+	t.Run("изменение сохранённого значения не влияет на хранилище", func(t *testing.T) {
+		repo := newRepo(t)
+		expr := &models.Expression{ID: "1", OwnerID: 1, Input: "x", Status: models.StatusPending, Variables: map[string]float64{"x": 1}}
+		if err := repo.SaveExpression(ctx, expr); err != nil {
+			t.Fatalf("SaveExpression() error = %v", err)
+		}
+		expr.Variables["x"] = 2
+
+		got, err := repo.GetExpression(ctx, "1")
+		if err != nil {
+			t.Fatalf("GetExpression() error = %v", err)
+		}
+		got.Variables["x"] = 3
+		again, err := repo.GetExpression(ctx, "1")
+		if err != nil || again.Variables["x"] != 1 {
+			t.Errorf("GetExpression() = %+v, %v, want x = 1", again, err)
+		}
+	})
+}
+
+// RunTaskRepositoryTests проверяет репозиторий задач. Задачи ссылаются на
+// выражения, поэтому newRepo возвращает оба репозитория одного хранилища.
+func RunTaskRepositoryTests(t *testing.T, newRepo func(t *testing.T) (storage.ExpressionRepository, storage.TaskRepository)) {
+	ctx := context.Background()
+
+	newTasks := func(t *testing.T) storage.TaskRepository {
+		expressions, tasks := newRepo(t)
+		if err := expressions.SaveExpression(ctx, &models.Expression{ID: "1", OwnerID: 1, Input: "max(1, 2) + 3", Status: models.StatusProcessing}); err != nil {
+			t.Fatalf("SaveExpression() error = %v", err)
+		}
+		return tasks
+	}
+
+	t.Run("сохранение и список", func(t *testing.T) {
+		tasks := newTasks(t)
+		fn := &models.Task{ID: "2", ExpressionID: "1", Args: []string{"1", "2"}, Operation: "max", OperationTime: 100}
+		add := &models.Task{ID: "3", ExpressionID: "1", Arg1: "task:2", Arg2: "3", Operation: "+", OperationTime: 100, Mode: models.ModeDecimal, Scale: 4}
+		for _, task := range []*models.Task{fn, add} {
+			if err := tasks.SaveTask(ctx, task); err != nil {
+				t.Fatalf("SaveTask(%s) error = %v", task.ID, err)
+			}
+		}
+
+		list, err := tasks.ListTasks(ctx)
+		if err != nil {
+			t.Fatalf("ListTasks() error = %v", err)
+		}
+		if len(list) != 2 {
+			t.Fatalf("ListTasks() = %+v, want two tasks", list)
+		}
+		if got := list[0]; got.ID != "2" || len(got.Args) != 2 || got.Args[1] != "2" || got.Operation != "max" || got.Result != nil {
+			t.Errorf("ListTasks()[0] = %+v, want %+v", got, fn)
+		}
+		if got := list[1]; got.ID != "3" || got.Arg1 != "task:2" || got.Arg2 != "3" || got.Args != nil ||
+			got.Mode != models.ModeDecimal || got.Scale != 4 || got.ExpressionID != "1" {
+			t.Errorf("ListTasks()[1] = %+v, want %+v", got, add)
+		}
+	})
+
+	t.Run("результат и ошибка задачи", func(t *testing.T) {
+		tasks := newTasks(t)
+		result := 2.0
+		done := &models.Task{ID: "2", ExpressionID: "1", Args: []string{"1", "2"}, Operation: "max"}
+		if err := tasks.SaveTask(ctx, done); err != nil {
+			t.Fatalf("SaveTask() error = %v", err)
+		}
+		done.Result = &result
+		done.Value = "2"
+		if err := tasks.SaveTask(ctx, done); err != nil {
+			t.Fatalf("SaveTask() update error = %v", err)
+		}
+		errMsg := "division by zero"
+		if err := tasks.SaveTask(ctx, &models.Task{ID: "3", ExpressionID: "1", Arg1: "1", Arg2: "0", Operation: "/", Error: &errMsg}); err != nil {
+			t.Fatalf("SaveTask() error = %v", err)
+		}
+
+		list, err := tasks.ListTasks(ctx)
+		if err != nil || len(list) != 2 {
+			t.Fatalf("ListTasks() = %+v, %v, want two tasks", list, err)
+		}
+		if got := list[0]; got.Result == nil || *got.Result != 2 || got.Value != "2" {
+			t.Errorf("ListTasks()[0] = %+v, want result 2", got)
+		}
+		if got := list[1]; got.Error == nil || *got.Error != errMsg || got.Result != nil {
+			t.Errorf("ListTasks()[1] = %+v, want error %q", got, errMsg)
+		}
+	})
+
+	t.Run("аренда не сохраняется", func(t *testing.T) {
+		tasks := newTasks(t)
+		if err := tasks.SaveTask(ctx, &models.Task{ID: "2", ExpressionID: "1", Arg1: "1", Arg2: "2", Operation: "+", LeaseID: "2-1"}); err != nil {
+			t.Fatalf("SaveTask() error = %v", err)
+		}
+		list, err := tasks.ListTasks(ctx)
+		if err != nil || len(list) != 1 || list[0].LeaseID != "" {
+			t.Errorf("ListTasks() = %+v, %v, want task without lease", list, err)
+		}
+	})
+}